@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"cacti-chess/engine/eval"
+	"cacti-chess/engine/nnue"
+	"cacti-chess/engine/search"
+	"cacti-chess/engine/tune"
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"log"
+	"os"
+	"runtime"
+	"time"
+)
+
+var datagenCmd = &cli.Command{
+	Name:  "datagen",
+	Usage: "plays the engine against itself, writing quiet positions labelled with their score and result",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "out",
+			Aliases: []string{"o"},
+			Usage:   "where to write the positions, as an epd that tune can read",
+			Value:   "positions.epd",
+		},
+		&cli.IntFlag{
+			Name:  "games",
+			Usage: "how many games to play",
+			Value: 1000,
+		},
+		&cli.IntFlag{
+			Name:  "threads",
+			Usage: "how many games are played at once",
+			Value: runtime.NumCPU(),
+		},
+		&cli.IntFlag{
+			Name:  "depth",
+			Usage: "how deep to search each move, ignored when there's a node limit",
+			Value: 6,
+		},
+		&cli.Uint64Flag{
+			Name:  "nodes",
+			Usage: "how many nodes to search each move, instead of a depth",
+		},
+		&cli.IntFlag{
+			Name:  "hash",
+			Usage: "the hash table size for each thread, in MB",
+			Value: 16,
+		},
+		&cli.IntFlag{
+			Name:  "random-plies",
+			Usage: "how many random moves start each game",
+			Value: 8,
+		},
+		&cli.Int64Flag{
+			Name:  "seed",
+			Usage: "seeds the random openings (default the current time)",
+		},
+		&cli.BoolFlag{
+			Name:  "skip-in-check",
+			Usage: "leave out positions where the side to move is in check",
+			Value: true,
+		},
+		&cli.BoolFlag{
+			Name:  "skip-tactical",
+			Usage: "leave out positions where the best move is a capture or promotion",
+			Value: true,
+		},
+		&cli.StringFlag{
+			Name:  "params",
+			Usage: "an optional params file, as written by tune (default built-in weights)",
+		},
+		&cli.StringFlag{
+			Name:  "nnue",
+			Usage: "an optional network to evaluate with, instead of the params",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		options := tune.GenOptions{
+			Games:        c.Int("games"),
+			Threads:      c.Int("threads"),
+			Depth:        c.Int("depth"),
+			Nodes:        c.Uint64("nodes"),
+			HashSize:     c.Int("hash"),
+			RandomPlies:  c.Int("random-plies"),
+			Seed:         c.Int64("seed"),
+			SkipInCheck:  c.Bool("skip-in-check"),
+			SkipTactical: c.Bool("skip-tactical"),
+		}
+		if !c.IsSet("seed") {
+			options.Seed = time.Now().UnixNano()
+		}
+		options.Scorer = datagenScorer(c.String("params"), c.String("nnue"))
+
+		file, err := os.Create(c.String("out"))
+		if err != nil {
+			log.Fatalf("could not create output: %v", err)
+		}
+		defer file.Close()
+		w := bufio.NewWriter(file)
+
+		fmt.Printf("playing %d games on %d threads, seed %d\n", options.Games, options.Threads, options.Seed)
+		start := time.Now()
+		games, positions := 0, 0
+		err = tune.Generate(options, func(game []tune.Entry) error {
+			if err := tune.WriteEPD(w, game); err != nil {
+				return err
+			}
+			games++
+			positions += len(game)
+			if games%100 == 0 || games == options.Games {
+				fmt.Printf("%d games, %d positions, %.1f games/s\n", games, positions, float64(games)/time.Since(start).Seconds())
+			}
+			return w.Flush()
+		})
+		if err != nil {
+			log.Fatalf("could not generate positions: %v", err)
+		}
+
+		fmt.Printf("positions written to %v\n", c.String("out"))
+		return nil
+	},
+}
+
+// datagenScorer is the evaluation the games are played with
+func datagenScorer(paramsPath, nnuePath string) search.Scorer {
+	if nnuePath != "" {
+		net, err := nnue.LoadNetwork(nnuePath)
+		if err != nil {
+			log.Fatalf("could not load network: %v", err)
+		}
+		return nnue.NewEvaluator(net)
+	}
+
+	scorer := eval.NewPositionEvaluator()
+	if paramsPath != "" {
+		params, err := eval.LoadParams(paramsPath)
+		if err != nil {
+			log.Fatalf("could not load params: %v", err)
+		}
+		scorer.SetParams(&params)
+	}
+	return scorer
+}
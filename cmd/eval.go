@@ -0,0 +1,48 @@
+package main
+
+import (
+	"cacti-chess/engine/eval"
+	"cacti-chess/engine/position"
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"log"
+)
+
+var evalCmd = &cli.Command{
+	Name:  "eval",
+	Usage: "shows how the static evaluation scores a position, term by term",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "fen",
+			Aliases: []string{"f"},
+			Usage:   "an optional fen to evaluate (default starting position)",
+		},
+		&cli.StringFlag{
+			Name:  "params",
+			Usage: "an optional params file, as written by tune (default built-in weights)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		fen := c.String("fen")
+		if fen == "" {
+			fen = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+		}
+		p, err := position.FromFen(fen)
+		if err != nil {
+			log.Fatalf("could not parse fen: %v", err)
+		}
+
+		scorer := eval.NewPositionEvaluator()
+		if path := c.String("params"); path != "" {
+			params, err := eval.LoadParams(path)
+			if err != nil {
+				log.Fatalf("could not load params: %v", err)
+			}
+			scorer.SetParams(&params)
+		}
+
+		fmt.Println(p)
+		fmt.Print(scorer.Trace(p))
+		return nil
+	},
+}
@@ -13,6 +13,9 @@ func main() {
 		Commands: []*cli.Command{
 			playgroundCmd,
 			playCmd,
+			tuneCmd,
+			evalCmd,
+			datagenCmd,
 		},
 	}
 
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"cacti-chess/engine/pgn"
 	"cacti-chess/engine/position"
 	"cacti-chess/engine/search"
 	"fmt"
@@ -9,6 +10,7 @@ import (
 	"log"
 	"os"
 	"strings"
+	"time"
 )
 
 var playCmd = &cli.Command{
@@ -26,6 +28,10 @@ var playCmd = &cli.Command{
 			Usage:   "play black",
 			Value:   false,
 		},
+		&cli.StringFlag{
+			Name:  "pgn",
+			Usage: "an optional pgn file to append the game to once it's over",
+		},
 	},
 	Action: func(c *cli.Context) error {
 		// read in flags
@@ -34,6 +40,7 @@ var playCmd = &cli.Command{
 			fen = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
 		}
 		playBlack := c.Bool("black")
+		pgnPath := c.String("pgn")
 
 		// initialize position
 		fmt.Printf("loading game from: %v\n", fen)
@@ -43,6 +50,18 @@ var playCmd = &cli.Command{
 			log.Fatalf("could not parse fen: %v", err)
 		}
 
+		// record the game as it's played
+		game := pgn.New(fen)
+		game.SetTag("Event", "cacti-chess play")
+		game.SetTag("Date", time.Now().Format("2006.01.02"))
+		if playBlack {
+			game.SetTag("White", "cacti-chess")
+			game.SetTag("Black", "player")
+		} else {
+			game.SetTag("White", "player")
+			game.SetTag("Black", "cacti-chess")
+		}
+
 		for true {
 			// print the board
 			fmt.Println(p)
@@ -51,16 +70,27 @@ var playCmd = &cli.Command{
 			if p.IsLegalMove() == false {
 				if p.IsStalemate() {
 					fmt.Println("Stalemate!")
+					game.SetResult(pgn.ResultDraw)
 				} else if p.IsCheckmate() {
 					fmt.Println("Checkmate!")
+					if p.GetSide() == position.WHITE {
+						game.SetResult(pgn.ResultBlackWins)
+					} else {
+						game.SetResult(pgn.ResultWhiteWins)
+					}
 				}
+				return saveGame(pgnPath, game)
 			}
 
 			// player/engine turn
 			if isPlayerTurn {
-				doPlayerTurn(p)
-			} else {
-				doEngineTurn(p)
+				if quit := doPlayerTurn(p, game); quit {
+					fmt.Println("exiting...")
+					return saveGame(pgnPath, game)
+				}
+			} else if played := doEngineTurn(p, game); !played {
+				fmt.Println("exiting...")
+				return saveGame(pgnPath, game)
 			}
 
 			// switch sides
@@ -71,44 +101,106 @@ var playCmd = &cli.Command{
 	},
 }
 
-// doPlayerTurn reads from stdin and makes the given move
-func doPlayerTurn(p *position.Position) {
-	reader := bufio.NewReader(os.Stdin)
+// stdinReader is shared between turns, so buffered input isn't lost
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// doPlayerTurn reads from stdin and makes the given move.
+// It returns true if the player wants to quit.
+func doPlayerTurn(p *position.Position, game *pgn.Game) bool {
 	// prompt until we get good input
 	for true {
 		// read in the next move
 		fmt.Print("Enter move: ")
-		mvStr, err := reader.ReadString('\n')
+		mvStr, err := stdinReader.ReadString('\n')
 		if err != nil {
 			log.Fatalf("error reading from stdin")
 		}
 
 		mvStr = strings.TrimSpace(mvStr)
 		if mvStr == "quit" || mvStr == "exit" {
-			fmt.Println("exiting...")
-			os.Exit(0)
+			return true
 		}
 
 		if mvStr == "undo" {
+			if len(game.Moves) < 2 {
+				fmt.Println("nothing to undo")
+				continue
+			}
 			p.UndoMove()
 			p.UndoMove()
+			game.Moves = game.Moves[:len(game.Moves)-2]
 			break
 		}
 
 		// parse the given move
-		movekey, err := p.ParseMove(mvStr)
-		if err != nil || movekey.IsNoMove() {
+		movekey, err := parseMoveStr(p, mvStr)
+		if err != nil || movekey.IsNoMove() || game.AddMove(p, movekey) != nil {
 			fmt.Println("move not recognized, try again")
 		} else {
-			p.MakeMove(movekey)
 			break
 		}
 	}
+	return false
 }
 
-// doEngineTurn searches for the best move and then performs it
-func doEngineTurn(p *position.Position) {
+// saveGame appends the game to a pgn file, if one was given
+func saveGame(path string, game *pgn.Game) error {
+	if path == "" {
+		return nil
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening pgn file: %v", err)
+	}
+	defer file.Close()
+
+	fmt.Printf("saving game to: %v\n", path)
+	return pgn.Write(file, game)
+}
+
+// doEngineTurn searches for the best move and then performs it.
+// It returns false if the engine couldn't find a move to play.
+func doEngineTurn(p *position.Position, game *pgn.Game) bool {
 	s := search.New()
+	s.SetListener(search.ListenerFunc(func(info search.Info) {
+		fmt.Println(formatInfo(p, info))
+	}))
 	_, line := s.SearchPosition(p, search.Options{Depth: 5})
-	p.MakeMove(line[0])
+	if len(line) == 0 {
+		fmt.Println("engine has no legal moves")
+		return false
+	}
+	fmt.Printf("engine plays: %v\n", p.ToSAN(line[0]))
+	game.AddMove(p, line[0])
+	return true
+}
+
+// formatInfo shows search progress in pawns with the line in SAN, i.e.
+// depth 3: +0.30 (nodes 1520, 12ms, ordering 0.91) e4 e5 Nf3
+func formatInfo(p *position.Position, info search.Info) string {
+	score := fmt.Sprintf("%+.2f", float64(info.Score)/100)
+	if info.IsMate {
+		score = fmt.Sprintf("mate %d", info.Mate)
+	}
+
+	// play out the line on a copy, since each move is needed to write the next
+	line := []string{}
+	c := p.Clone()
+	for _, mv := range info.PV {
+		line = append(line, c.ToSAN(mv))
+		c.MakeMove(mv)
+	}
+
+	return fmt.Sprintf("depth %d: %s (nodes %d, %dms, ordering %.2f) %s", info.Depth, score, info.Nodes, info.Time.Milliseconds(), info.Ordering, strings.Join(line, " "))
+}
+
+// parseMoveStr accepts either a UCI style move (e2e4, h7h8q)
+// or a SAN move (e4, Nf3, O-O, h8=Q)
+func parseMoveStr(p *position.Position, mvStr string) (position.Movekey, error) {
+	movekey, err := p.ParseMove(mvStr)
+	if err == nil && !movekey.IsNoMove() {
+		return movekey, nil
+	}
+	return p.ParseSAN(mvStr)
 }
@@ -52,7 +52,7 @@ var playgroundCmd = &cli.Command{
 				}
 
 				// parse the given move
-				movekey, err := p.ParseMove(mvStr)
+				movekey, err := parseMoveStr(p, mvStr)
 				if err != nil || movekey.IsNoMove() {
 					fmt.Println("move not recognized, try again")
 				} else {
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"cacti-chess/engine/eval"
+	"cacti-chess/engine/tune"
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"log"
+	"os"
+	"runtime"
+)
+
+var tuneCmd = &cli.Command{
+	Name:  "tune",
+	Usage: "tunes the evaluation weights to game results with Texel's method",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "epd",
+			Usage:    "an epd of quiet positions, with the game result in the c9 opcode",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "params",
+			Usage: "an optional params file to start from (default built-in weights)",
+		},
+		&cli.StringFlag{
+			Name:    "out",
+			Aliases: []string{"o"},
+			Usage:   "where to write the tuned params, after every pass",
+			Value:   "eval_params.toml",
+		},
+		&cli.IntFlag{
+			Name:  "passes",
+			Usage: "the most passes to make over every weight",
+			Value: 100,
+		},
+		&cli.IntFlag{
+			Name:  "threads",
+			Usage: "how many threads evaluate positions",
+			Value: runtime.NumCPU(),
+		},
+	},
+	Action: func(c *cli.Context) error {
+		params := eval.DefaultParams()
+		if path := c.String("params"); path != "" {
+			var err error
+			params, err = eval.LoadParams(path)
+			if err != nil {
+				log.Fatalf("could not load params: %v", err)
+			}
+		}
+
+		file, err := os.Open(c.String("epd"))
+		if err != nil {
+			log.Fatalf("could not open epd: %v", err)
+		}
+		entries, err := tune.ReadEPD(file)
+		file.Close()
+		if err != nil {
+			log.Fatalf("could not read epd: %v", err)
+		}
+		fmt.Printf("loaded %d positions\n", len(entries))
+
+		tuner := tune.NewTuner(entries, c.Int("threads"))
+		k := tuner.FitK(&params)
+		fmt.Printf("k: %.4f, error: %.6f\n", k, tuner.Error(&params))
+
+		out := c.String("out")
+		tuner.Tune(&params, c.Int("passes"), func(pass int, err float64) {
+			fmt.Printf("pass %d, error: %.6f\n", pass, err)
+			if err := writeParams(out, &params); err != nil {
+				log.Fatalf("could not write params: %v", err)
+			}
+		})
+
+		fmt.Printf("tuned params written to %v\n", out)
+		return nil
+	},
+}
+
+// writeParams saves params to a file, replacing it
+func writeParams(path string, params *eval.Params) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := params.Write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -2,77 +2,115 @@ package eval
 
 import "cacti-chess/engine/position"
 
-type PositionEvaluator struct{}
+type PositionEvaluator struct {
+	params *Params    // nil for the built-in weights
+	pawns  *PawnTable // caches the pawn structure, nil to work it out every time
+}
+
+// NewPositionEvaluator creates an evaluator with its own pawn table
+func NewPositionEvaluator() *PositionEvaluator {
+	return &PositionEvaluator{pawns: NewPawnTable(DefaultPawnTableSize)}
+}
 
-// Evaluate takes a position and returns a value based on the material
-// and positional advantages. A positive number is an advantage for
-// white, negative for black. Returned unit is in 100th of a pawn.
-func (s PositionEvaluator) Evaluate(p *position.Position) float64 {
-	// calculate initial material
+// SetParams changes the weights the evaluator uses. Any pawn table is
+// replaced, since the pawn structure it cached was for the old weights.
+func (s *PositionEvaluator) SetParams(params *Params) {
+	s.params = params
+	if s.pawns != nil {
+		s.pawns = NewPawnTable(len(s.pawns.slots))
+	}
+}
+
+// PhaseMax is the game phase with every piece still on the board,
+// counting down to 0 when only kings and pawns are left
+const PhaseMax = 256
+
+// phaseMaterial is the non-pawn material both sides start with
+var phaseMaterial = 2 * (2*position.PwN.Value() + 2*position.PwB.Value() + 2*position.PwR.Value() + position.PwQ.Value())
+
+// Phase is how far from the endgame a position is, from PhaseMax at the
+// start to 0 with only kings and pawns. It's the non-pawn material left
+// on the board, so trading queens moves it much closer to the endgame
+// than trading knights.
+func Phase(p *position.Position) int {
 	material := p.GetMaterial()
 	pceCount := p.GetPieceCount()
-	pceList := p.GetPieceList()
 
-	score := material[position.WHITE] - material[position.BLACK]
+	nonPawn := material[position.WHITE] + material[position.BLACK] -
+		(pceCount[position.PwP]+pceCount[position.PbP])*position.PwP.Value() -
+		2*position.PwK.Value()
 
-	//calculate
-	//piece
-	//squares
-	pce := position.PwP
-	for i := 0; i < pceCount[pce]; i++ {
-		sq120 := pceList[pce][i]
-		score += pawnTable[position.SQ64(sq120)]
+	// promotions can put more material on the board than at the start
+	if nonPawn > phaseMaterial {
+		nonPawn = phaseMaterial
 	}
+	return nonPawn * PhaseMax / phaseMaterial
+}
 
-	pce = position.PbP
-	for i := 0; i < pceCount[pce]; i++ {
-		sq120 := pceList[pce][i]
-		score -= pawnTable[mirror64[position.SQ64(sq120)]]
-	}
+/*
+Evaluate takes a position and returns a value based on the material
+and positional advantages. A positive number is an advantage for
+white, negative for black. Returned unit is in 100th of a pawn.
 
-	pce = position.PwN
-	for i := 0; i < pceCount[pce]; i++ {
-		sq120 := pceList[pce][i]
-		score += knightTable[position.SQ64(sq120)]
-	}
+Where a piece belongs changes over the game, i.e. the king should hide
+behind its pawns while there are pieces around to attack it, but come
+out and fight once they're gone. So each piece square is scored for
+the middlegame and the endgame, and the two are tapered together by
+the game phase. That way there's no sudden jump in the evaluation
+when the position crosses from one to the other.
 
-	pce = position.PbN
-	for i := 0; i < pceCount[pce]; i++ {
-		sq120 := pceList[pce][i]
-		score -= knightTable[mirror64[position.SQ64(sq120)]]
-	}
+The pawn structure, king safety and mobility are scored the same way,
+see pawnStructure, kingSafety and evaluatePieces. Trace breaks the
+evaluation down into these terms.
+*/
+func (s PositionEvaluator) Evaluate(p *position.Position) Score {
+	return s.evaluate(p, nil)
+}
 
-	pce = position.PwB
-	for i := 0; i < pceCount[pce]; i++ {
-		sq120 := pceList[pce][i]
-		score += bishopTable[position.SQ64(sq120)]
+// evaluate adds up every term, keeping them in the trace if there is one
+func (s PositionEvaluator) evaluate(p *position.Position, trace *Trace) Score {
+	params := s.params
+	if params == nil {
+		params = &defaultParams
 	}
+	e := evaluation{params: params, trace: trace}
 
-	pce = position.PbB
-	for i := 0; i < pceCount[pce]; i++ {
-		sq120 := pceList[pce][i]
-		score -= bishopTable[mirror64[position.SQ64(sq120)]]
+	// material, without the kings which are always on the board
+	pceCount := p.GetPieceCount()
+	for i, pieces := range pieceTypes[:5] {
+		for color, pce := range pieces {
+			e.add(TermMaterial, color, pceCount[pce]*params.MaterialMg[i], pceCount[pce]*params.MaterialEg[i])
+		}
 	}
 
-	pce = position.PwR
-	for i := 0; i < pceCount[pce]; i++ {
-		sq120 := pceList[pce][i]
-		score += rookTable[position.SQ64(sq120)]
+	// piece squares, black's are mirrored to read them from white's side
+	pceList := p.GetPieceList()
+	for i, pieces := range pieceTypes {
+		mg, eg := &params.PieceSquareMg[i], &params.PieceSquareEg[i]
+		for n := 0; n < pceCount[pieces[position.WHITE]]; n++ {
+			sq := position.SQ64(pceList[pieces[position.WHITE]][n])
+			e.add(TermPawnSquares+Term(i), position.WHITE, mg[sq], eg[sq])
+		}
+		for n := 0; n < pceCount[pieces[position.BLACK]]; n++ {
+			sq := mirror64[position.SQ64(pceList[pieces[position.BLACK]][n])]
+			e.add(TermPawnSquares+Term(i), position.BLACK, mg[sq], eg[sq])
+		}
 	}
 
-	pce = position.PbR
-	for i := 0; i < pceCount[pce]; i++ {
-		sq120 := pceList[pce][i]
-		score -= rookTable[mirror64[position.SQ64(sq120)]]
-	}
+	s.evaluatePawns(p, &e)
+	evaluatePieces(p, &e)
 
-	return float64(score)
+	phase := Phase(p)
+	if trace != nil {
+		trace.Phase = phase
+	}
+	return Score((e.mg*phase + e.eg*(PhaseMax-phase)) / PhaseMax)
 }
 
 // EvaluateAbsolute returns the same evaluation as Evaluate, but will
 // always return a positive value even for black. This can be used
 // for negamax implementations of minimax
-func (s PositionEvaluator) EvaluateAbsolute(p *position.Position) float64 {
+func (s PositionEvaluator) EvaluateAbsolute(p *position.Position) Score {
 	if p.GetSide() == position.WHITE {
 		return s.Evaluate(p)
 	} else {
@@ -10,7 +10,7 @@ import (
 type testCaseScore struct {
 	name string
 	fen  string
-	want float64
+	want Score
 }
 
 func (tc testCaseScore) assert(t *testing.T) {
@@ -39,12 +39,12 @@ func TestPositionScorer_Score(t *testing.T) {
 		{
 			"black down a rook",
 			"rnbqkbn1/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
-			550,
+			534,
 		},
 		{
 			"wN move",
 			"rnbqkbnr/pppppppp/8/8/8/2N5/PPPPPPPP/R1BQKBNR w KQkq - 0 1",
-			20,
+			34,
 		},
 	}
 
@@ -52,3 +52,51 @@ func TestPositionScorer_Score(t *testing.T) {
 		tc.assert(t)
 	}
 }
+
+func TestPhase(t *testing.T) {
+	type testCase struct {
+		name string
+		fen  string
+		want int
+	}
+
+	for _, tc := range []testCase{
+		{"starting position", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", PhaseMax},
+		{"kings and pawns", "4k3/pppppppp/8/8/8/8/PPPPPPPP/4K3 w - - 0 1", 0},
+		{"queens traded", "rnb1kbnr/pppppppp/8/8/8/8/PPPPPPPP/RNB1KBNR w KQkq - 0 1", (phaseMaterial - 2000) * PhaseMax / phaseMaterial},
+		{"promoted queens", "qqbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", PhaseMax},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := position.FromFen(tc.fen)
+			require.Nil(t, err)
+			assert.Equal(t, tc.want, Phase(p))
+		})
+	}
+}
+
+func TestPositionEvaluator_tapered(t *testing.T) {
+	scr := PositionEvaluator{}
+	evaluate := func(fen string) Score {
+		p, err := position.FromFen(fen)
+		require.Nil(t, err)
+		return scr.Evaluate(p)
+	}
+
+	t.Run("it wants the king castled in the middlegame", func(t *testing.T) {
+		castled := evaluate("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQ1RK1 w kq - 0 1")
+		central := evaluate("rnbqkbnr/pppppppp/8/8/8/4K3/PPPPPPPP/RNBQ3R w kq - 0 1")
+		assert.Greater(t, castled, central)
+	})
+
+	t.Run("it wants the king central in the endgame", func(t *testing.T) {
+		corner := evaluate("4k3/pppp4/8/8/8/8/PPPP4/6K1 w - - 0 1")
+		central := evaluate("4k3/pppp4/8/8/4K3/8/PPPP4/8 w - - 0 1")
+		assert.Greater(t, central, corner)
+	})
+
+	t.Run("it scores queens", func(t *testing.T) {
+		home := evaluate("4k3/8/8/8/8/8/8/3QK3 w - - 0 1")
+		central := evaluate("4k3/8/8/8/3Q4/8/8/4K3 w - - 0 1")
+		assert.Greater(t, central, home)
+	})
+}
@@ -0,0 +1,87 @@
+package eval
+
+import (
+	"cacti-chess/engine/position"
+	"math/bits"
+)
+
+// The built-in king safety terms, which only count in the middlegame. The shield and
+// storm are indexed by how many ranks a pawn is in front of the king.
+var (
+	kingShield   = [8]int{0, 15, 8, 3, 0, 0, 0, 0}
+	kingStorm    = [8]int{0, -5, -25, -15, -5, 0, 0, 0}
+	kingOpenFile = -25 // no pawns at all
+	kingHalfFile = -12 // no pawns of the king's own side
+
+	// per square of the king zone attacked, for knights, bishops, rooks and queens
+	kingAttackWeight = [4]int{20, 20, 40, 80}
+
+	// how much of the attack weight counts, in percent, by number of attackers
+	kingAttackScale = [8]int{0, 0, 50, 75, 88, 94, 97, 99}
+)
+
+// kingAttack is how strongly the pieces of one side attack the enemy king
+type kingAttack struct {
+	attackers int // pieces attacking the king zone
+	weight    int // attacked squares, weighted by the attacking piece
+}
+
+// kingZone is the king's square and every square around it
+func kingZone(p *position.Position, kingSq int) uint64 {
+	return p.GetAttacks(kingSq) | 1<<position.SQ64(kingSq)
+}
+
+/*
+kingSafety scores the king of a side by looking at the files either side
+of it, and at the enemy pieces attacking the squares around it.
+
+  - the shield is the king's own pawns in front of it, best when they haven't
+    moved, since they keep pieces from getting to the king
+  - the storm is enemy pawns coming up the board to break open the shield
+  - open files next to the king let rooks and queens in
+  - the attack needs more than one piece before it's really dangerous, so
+    its weight is scaled up by the number of attackers
+*/
+func kingSafety(e *evaluation, color, kingSq int, pawns [3]uint64, attack kingAttack) {
+	file, rank := kingSq%8, kingSq/8
+	ahead := forwardRanks[color][rank]
+
+	params := e.params
+	shield, storm, files := 0, 0, 0
+	for f := file - 1; f <= file+1; f++ {
+		if f < 0 || f > 7 {
+			continue
+		}
+
+		if own := fileMasks[f] & ahead & pawns[color]; own != 0 {
+			shield += params.KingShield[rankDistance(color, own, rank)]
+		}
+		if enemy := fileMasks[f] & ahead & pawns[color^1]; enemy != 0 {
+			storm += params.KingStorm[rankDistance(color, enemy, rank)]
+		}
+
+		if fileMasks[f]&pawns[position.BOTH] == 0 {
+			files += params.KingOpenFile
+		} else if fileMasks[f]&pawns[color] == 0 {
+			files += params.KingHalfFile
+		}
+	}
+	e.add(TermKingShield, color, shield, 0)
+	e.add(TermKingStorm, color, storm, 0)
+	e.add(TermKingFiles, color, files, 0)
+
+	attackers := attack.attackers
+	if attackers >= len(params.KingAttackScale) {
+		attackers = len(params.KingAttackScale) - 1
+	}
+	e.add(TermKingAttack, color, -attack.weight*params.KingAttackScale[attackers]/100, 0)
+}
+
+// rankDistance is how many ranks in front of a rank the nearest pawn is,
+// for pawns that are all in front of it from the side's point of view
+func rankDistance(color int, pawns uint64, rank int) int {
+	if color == position.WHITE {
+		return bits.TrailingZeros64(pawns)/8 - rank
+	}
+	return rank - (63-bits.LeadingZeros64(pawns))/8
+}
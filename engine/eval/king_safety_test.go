@@ -0,0 +1,82 @@
+package eval
+
+import (
+	"cacti-chess/engine/position"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_kingSafety(t *testing.T) {
+	type testCase struct {
+		name  string
+		fen   string
+		color int
+		term  Term
+		want  int // middlegame score
+	}
+
+	for _, tc := range []testCase{
+		{
+			"full shield",
+			"6k1/5ppp/8/8/8/8/5PPP/6K1 w - - 0 1",
+			position.WHITE, TermKingShield, 3 * kingShield[1],
+		},
+		{
+			"pushed shield pawn",
+			"6k1/5ppp/8/8/8/7P/5PP1/6K1 w - - 0 1",
+			position.WHITE, TermKingShield, 2*kingShield[1] + kingShield[2],
+		},
+		{
+			"black shield",
+			"6k1/5pp1/7p/8/8/8/5PPP/6K1 w - - 0 1",
+			position.BLACK, TermKingShield, 2*kingShield[1] + kingShield[2],
+		},
+		{
+			"shield on the edge",
+			"7k/6pp/8/8/8/8/6PP/7K w - - 0 1",
+			position.WHITE, TermKingShield, 2 * kingShield[1],
+		},
+		{
+			"pawn storm",
+			"6k1/5ppp/8/8/6p1/8/5PP1/6K1 w - - 0 1",
+			position.WHITE, TermKingStorm, kingStorm[3],
+		},
+		{
+			"open file",
+			"6k1/5p1p/8/8/8/8/5P1P/6K1 w - - 0 1",
+			position.WHITE, TermKingFiles, kingOpenFile,
+		},
+		{
+			"half open file",
+			"6k1/5ppp/8/8/8/8/5P1P/6K1 w - - 0 1",
+			position.WHITE, TermKingFiles, kingHalfFile,
+		},
+		{
+			"no attackers",
+			"6k1/5ppp/8/8/8/8/5PPP/3Q2K1 b - - 0 1",
+			position.WHITE, TermKingAttack, 0,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := position.FromFen(tc.fen)
+			require.Nil(t, err)
+
+			trace := PositionEvaluator{}.Trace(p)
+			assert.Equal(t, tc.want, trace.Terms[tc.term][tc.color].Mg)
+		})
+	}
+
+	t.Run("it scales the attack by the number of attackers", func(t *testing.T) {
+		attack := func(fen string) int {
+			p, err := position.FromFen(fen)
+			require.Nil(t, err)
+			return PositionEvaluator{}.Trace(p).Terms[TermKingAttack][position.BLACK].Mg
+		}
+
+		queen := attack("6k1/5ppp/8/7Q/8/8/5PPP/6K1 w - - 0 1")
+		queenAndKnight := attack("6k1/5ppp/8/6NQ/8/8/5PPP/6K1 w - - 0 1")
+		assert.Equal(t, 0, queen)
+		assert.Less(t, queenAndKnight, 0)
+	})
+}
@@ -0,0 +1,69 @@
+package eval
+
+import (
+	"cacti-chess/engine/position"
+	"math/bits"
+)
+
+// The pieces scored for mobility and attacking the king, for white/black
+var mobilityPieces = [2][4]position.Piece{
+	{position.PwN, position.PwB, position.PwR, position.PwQ},
+	{position.PbN, position.PbB, position.PbR, position.PbQ},
+}
+
+// The built-in mobility terms for knights, bishops, rooks and queens. A piece scores
+// for each square it can move to more than its base, and loses for each
+// square fewer, so a typical piece is around 0.
+var (
+	mobilityBase = [4]int{4, 7, 7, 14}
+	mobilityMg   = [4]int{4, 5, 2, 1}
+	mobilityEg   = [4]int{4, 5, 4, 2}
+)
+
+/*
+evaluatePieces scores the mobility of every piece, counted from its pseudo
+legal moves, i.e. the squares it attacks that aren't taken by its own side.
+Since the attacks are already worked out, the pieces attacking the enemy
+king are counted at the same time and passed to kingSafety.
+*/
+func evaluatePieces(p *position.Position, e *evaluation) {
+	pceCount := p.GetPieceCount()
+	pceList := p.GetPieceList()
+
+	var occupied [2]uint64
+	for pce := position.PwP; pce <= position.PbK; pce++ {
+		color := position.WHITE
+		if pce >= position.PbP {
+			color = position.BLACK
+		}
+		for i := 0; i < pceCount[pce]; i++ {
+			occupied[color] |= 1 << position.SQ64(pceList[pce][i])
+		}
+	}
+
+	kingSq := [2]int{pceList[position.PwK][0], pceList[position.PbK][0]}
+	zones := [2]uint64{kingZone(p, kingSq[position.WHITE]), kingZone(p, kingSq[position.BLACK])}
+
+	var attacks [2]kingAttack
+	for color := position.WHITE; color <= position.BLACK; color++ {
+		enemyZone := zones[color^1]
+		for i, pce := range mobilityPieces[color] {
+			for n := 0; n < pceCount[pce]; n++ {
+				moves := p.GetAttacks(pceList[pce][n]) &^ occupied[color]
+
+				mobility := bits.OnesCount64(moves) - e.params.MobilityBase[i]
+				e.add(TermMobility, color, mobility*e.params.MobilityMg[i], mobility*e.params.MobilityEg[i])
+
+				if hits := bits.OnesCount64(moves & enemyZone); hits > 0 {
+					attacks[color].attackers++
+					attacks[color].weight += hits * e.params.KingAttackWeight[i]
+				}
+			}
+		}
+	}
+
+	pawns := p.GetPawns()
+	for color := position.WHITE; color <= position.BLACK; color++ {
+		kingSafety(e, color, position.SQ64(kingSq[color]), pawns, attacks[color^1])
+	}
+}
@@ -0,0 +1,42 @@
+package eval
+
+import (
+	"cacti-chess/engine/position"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_evaluatePieces(t *testing.T) {
+	mobility := func(fen string) TermScore {
+		p, err := position.FromFen(fen)
+		require.Nil(t, err)
+		return PositionEvaluator{}.Trace(p).Terms[TermMobility][position.WHITE]
+	}
+
+	t.Run("it scores pseudo legal moves", func(t *testing.T) {
+		// 8 knight moves, less the one taken by its own pawn
+		assert.Equal(t,
+			TermScore{(7 - mobilityBase[0]) * mobilityMg[0], (7 - mobilityBase[0]) * mobilityEg[0]},
+			mobility("4k3/8/8/8/3N4/8/4P3/K7 w - - 0 1"),
+		)
+	})
+
+	t.Run("it includes captures", func(t *testing.T) {
+		assert.Equal(t, mobility("4k3/8/8/8/8/8/8/R5K1 w - - 0 1"), mobility("4k3/8/8/8/8/8/8/R4nK1 w - - 0 1"))
+	})
+
+	t.Run("it prefers active pieces", func(t *testing.T) {
+		corner := mobility("4k3/8/8/8/8/8/8/N3K3 w - - 0 1")
+		central := mobility("4k3/8/8/8/3N4/8/8/4K3 w - - 0 1")
+		assert.Greater(t, central.Mg, corner.Mg)
+		assert.Greater(t, central.Eg, corner.Eg)
+	})
+
+	t.Run("it's the same for both sides at the start", func(t *testing.T) {
+		p, err := position.FromFen("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+		require.Nil(t, err)
+		trace := PositionEvaluator{}.Trace(p)
+		assert.Equal(t, trace.Terms[TermMobility][position.WHITE], trace.Terms[TermMobility][position.BLACK])
+	})
+}
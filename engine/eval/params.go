@@ -0,0 +1,119 @@
+package eval
+
+import (
+	"cacti-chess/engine/position"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"io"
+	"os"
+)
+
+/*
+Params are the weights of every evaluation term, so they can be changed
+without recompiling, i.e. by a file from the tune command. Pieces are in
+the order pawn, knight, bishop, rook, queen, king, and piece squares are
+from white's side, A1 first.
+
+Middlegame and endgame weights are kept in separate arrays, since that's
+much easier to read and edit in a file than pairs. Fields tagged with
+tune:"-" aren't weights, and aren't changed by tuning.
+*/
+type Params struct {
+	MaterialMg [5]int `toml:"material_mg"` // no king, it can't be traded
+	MaterialEg [5]int `toml:"material_eg"`
+
+	PieceSquareMg [6][64]int `toml:"piece_square_mg"`
+	PieceSquareEg [6][64]int `toml:"piece_square_eg"`
+
+	PassedPawnMg     [8]int `toml:"passed_pawn_mg"` // by relative rank
+	PassedPawnEg     [8]int `toml:"passed_pawn_eg"`
+	PassedPawnFreeEg [8]int `toml:"passed_pawn_free_eg"`
+	ConnectedPawn    [8]int `toml:"connected_pawn"`
+	IsolatedPawnMg   int    `toml:"isolated_pawn_mg"`
+	IsolatedPawnEg   int    `toml:"isolated_pawn_eg"`
+	DoubledPawnMg    int    `toml:"doubled_pawn_mg"`
+	DoubledPawnEg    int    `toml:"doubled_pawn_eg"`
+	BackwardPawnMg   int    `toml:"backward_pawn_mg"`
+	BackwardPawnEg   int    `toml:"backward_pawn_eg"`
+
+	KingShield       [8]int `toml:"king_shield"` // by ranks in front of the king
+	KingStorm        [8]int `toml:"king_storm"`
+	KingOpenFile     int    `toml:"king_open_file"`
+	KingHalfFile     int    `toml:"king_half_file"`
+	KingAttackWeight [4]int `toml:"king_attack_weight"` // knight, bishop, rook, queen
+	KingAttackScale  [8]int `toml:"king_attack_scale"`  // percent, by number of attackers
+
+	MobilityBase [4]int `toml:"mobility_base" tune:"-"` // knight, bishop, rook, queen
+	MobilityMg   [4]int `toml:"mobility_mg"`
+	MobilityEg   [4]int `toml:"mobility_eg"`
+}
+
+// DefaultParams are the built-in weights
+func DefaultParams() Params {
+	material := [5]int{
+		position.PwP.Value(), position.PwN.Value(), position.PwB.Value(),
+		position.PwR.Value(), position.PwQ.Value(),
+	}
+
+	return Params{
+		MaterialMg: material,
+		MaterialEg: material,
+
+		PieceSquareMg: [6][64]int{pawnTableMg, knightTableMg, bishopTableMg, rookTableMg, queenTableMg, kingTableMg},
+		PieceSquareEg: [6][64]int{pawnTableEg, knightTableEg, bishopTableEg, rookTableEg, queenTableEg, kingTableEg},
+
+		PassedPawnMg:     passedPawnMg,
+		PassedPawnEg:     passedPawnEg,
+		PassedPawnFreeEg: passedPawnFreeEg,
+		ConnectedPawn:    connectedPawn,
+		IsolatedPawnMg:   isolatedPawnMg,
+		IsolatedPawnEg:   isolatedPawnEg,
+		DoubledPawnMg:    doubledPawnMg,
+		DoubledPawnEg:    doubledPawnEg,
+		BackwardPawnMg:   backwardPawnMg,
+		BackwardPawnEg:   backwardPawnEg,
+
+		KingShield:       kingShield,
+		KingStorm:        kingStorm,
+		KingOpenFile:     kingOpenFile,
+		KingHalfFile:     kingHalfFile,
+		KingAttackWeight: kingAttackWeight,
+		KingAttackScale:  kingAttackScale,
+
+		MobilityBase: mobilityBase,
+		MobilityMg:   mobilityMg,
+		MobilityEg:   mobilityEg,
+	}
+}
+
+// defaultParams are used by evaluators without their own
+var defaultParams = DefaultParams()
+
+// ReadParams reads weights in TOML, starting from the built-in weights
+// so a file only needs the ones it changes
+func ReadParams(r io.Reader) (Params, error) {
+	params := DefaultParams()
+	meta, err := toml.DecodeReader(r, &params)
+	if err != nil {
+		return Params{}, err
+	}
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		return Params{}, fmt.Errorf("unknown params: %v", undecoded)
+	}
+	return params, nil
+}
+
+// LoadParams reads weights from a TOML file, see ReadParams
+func LoadParams(path string) (Params, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Params{}, err
+	}
+	defer file.Close()
+	return ReadParams(file)
+}
+
+// Write writes the weights in TOML, as read by ReadParams
+func (params *Params) Write(w io.Writer) error {
+	return toml.NewEncoder(w).Encode(params)
+}
@@ -0,0 +1,62 @@
+package eval
+
+import (
+	"bytes"
+	"cacti-chess/engine/position"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestParams(t *testing.T) {
+	t.Run("it round trips the weights", func(t *testing.T) {
+		params := DefaultParams()
+		params.PieceSquareEg[5][27] = -123
+		params.KingOpenFile = 7
+
+		buf := &bytes.Buffer{}
+		require.Nil(t, params.Write(buf))
+
+		read, err := ReadParams(buf)
+		require.Nil(t, err)
+		assert.Equal(t, params, read)
+	})
+
+	t.Run("it defaults anything not in the file", func(t *testing.T) {
+		read, err := ReadParams(strings.NewReader("isolated_pawn_mg = -30\nmobility_mg = [1, 2, 3, 4]\n"))
+		require.Nil(t, err)
+
+		want := DefaultParams()
+		want.IsolatedPawnMg = -30
+		want.MobilityMg = [4]int{1, 2, 3, 4}
+		assert.Equal(t, want, read)
+	})
+
+	t.Run("it rejects unknown params", func(t *testing.T) {
+		_, err := ReadParams(strings.NewReader("isolated_pawns = -30\n"))
+		assert.NotNil(t, err)
+	})
+
+	t.Run("it rejects bad files", func(t *testing.T) {
+		_, err := ReadParams(strings.NewReader("material_mg = 100\n"))
+		assert.NotNil(t, err)
+	})
+}
+
+func TestPositionEvaluator_SetParams(t *testing.T) {
+	p, err := position.FromFen("4k3/8/8/8/8/8/PPPP4/4K3 w - - 0 1")
+	require.Nil(t, err)
+
+	scr := NewPositionEvaluator()
+	before := scr.Evaluate(p)
+
+	params := DefaultParams()
+	params.MaterialEg[0] += 50
+	params.ConnectedPawn[1] += 10
+	scr.SetParams(&params)
+
+	// with only pawns it's all endgame, and the pawn structure
+	// cached with the old weights isn't used
+	assert.Equal(t, before+4*50+4*10, scr.Evaluate(p))
+}
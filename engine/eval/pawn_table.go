@@ -0,0 +1,74 @@
+package eval
+
+import "sync/atomic"
+
+// DefaultPawnTableSize is how many entries a PawnTable has when none is given
+const DefaultPawnTableSize = 1 << 16
+
+// pawnEntry is the pawn structure score for a pawn key
+type pawnEntry struct {
+	key    uint64
+	scores [2]TermScore
+	passed uint64
+}
+
+// pawnSlot is an entry as it's stored in the table. Like the search's
+// transposition table, every thread reads and writes slots without locking,
+// so the key is stored XORed with the rest. A slot torn between two writes
+// won't match its key, and is treated as a miss.
+type pawnSlot struct {
+	check  uint64 // key ^ score ^ passed
+	score  uint64 // both sides' scores packed into 16 bits each
+	passed uint64
+}
+
+/*
+PawnTable caches the pawn structure by pawn key. Pawns move far less often
+than pieces, so most positions in a search share their pawns with positions
+already evaluated, and the pawn structure only needs working out once.
+*/
+type PawnTable struct {
+	slots []pawnSlot
+}
+
+// NewPawnTable creates a table with a number of entries
+func NewPawnTable(size int) *PawnTable {
+	if size < 1 {
+		size = 1
+	}
+	return &PawnTable{slots: make([]pawnSlot, size)}
+}
+
+func (pt *PawnTable) slot(key uint64) *pawnSlot {
+	return &pt.slots[key%uint64(len(pt.slots))]
+}
+
+// probe looks up the pawn structure for a pawn key
+func (pt *PawnTable) probe(key uint64) (pawnEntry, bool) {
+	slot := pt.slot(key)
+	score := atomic.LoadUint64(&slot.score)
+	passed := atomic.LoadUint64(&slot.passed)
+	check := atomic.LoadUint64(&slot.check)
+
+	if check^score^passed != key || score == 0 && passed == 0 && check == 0 {
+		return pawnEntry{}, false
+	}
+	e := pawnEntry{key: key, passed: passed}
+	for color := range e.scores {
+		e.scores[color].Mg = int(int16(score >> (color*32 + 16)))
+		e.scores[color].Eg = int(int16(score >> (color * 32)))
+	}
+	return e, true
+}
+
+// store saves the pawn structure for a pawn key, replacing whatever was there
+func (pt *PawnTable) store(e pawnEntry) {
+	slot := pt.slot(e.key)
+	score := uint64(0)
+	for color, s := range e.scores {
+		score |= uint64(uint16(int16(s.Mg)))<<(color*32+16) | uint64(uint16(int16(s.Eg)))<<(color*32)
+	}
+	atomic.StoreUint64(&slot.score, score)
+	atomic.StoreUint64(&slot.passed, e.passed)
+	atomic.StoreUint64(&slot.check, e.key^score^e.passed)
+}
@@ -0,0 +1,45 @@
+package eval
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPawnTable(t *testing.T) {
+	t.Run("it stores and probes entries", func(t *testing.T) {
+		pt := NewPawnTable(16)
+		want := pawnEntry{
+			key:    0xdeadbeef,
+			scores: [2]TermScore{{-35, 120}, {12, -7}},
+			passed: 1<<12 | 1<<52,
+		}
+		pt.store(want)
+
+		got, ok := pt.probe(want.key)
+		assert.True(t, ok)
+		assert.Equal(t, want, got)
+	})
+
+	t.Run("it misses on a different key in the same slot", func(t *testing.T) {
+		pt := NewPawnTable(16)
+		pt.store(pawnEntry{key: 1, scores: [2]TermScore{{10, 20}}})
+
+		_, ok := pt.probe(17)
+		assert.False(t, ok)
+	})
+
+	t.Run("it misses on an empty table", func(t *testing.T) {
+		pt := NewPawnTable(16)
+		_, ok := pt.probe(0)
+		assert.False(t, ok)
+	})
+
+	t.Run("it misses on a torn slot", func(t *testing.T) {
+		pt := NewPawnTable(16)
+		pt.store(pawnEntry{key: 3, scores: [2]TermScore{{10, 20}}})
+		pt.slot(3).score ^= 1
+
+		_, ok := pt.probe(3)
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,198 @@
+package eval
+
+import (
+	"cacti-chess/engine/position"
+	"math/bits"
+)
+
+// The built-in pawn structure terms, as middlegame/endgame pairs. Passed pawns
+// are indexed by rank from the pawn's own side, so the 7th rank is index 6.
+var (
+	passedPawnMg     = [8]int{0, 5, 10, 15, 25, 40, 60, 0}
+	passedPawnEg     = [8]int{0, 10, 15, 25, 45, 70, 110, 0}
+	passedPawnFreeEg = [8]int{0, 0, 5, 10, 20, 35, 60, 0} // nothing in the way of promoting
+	connectedPawn    = [8]int{0, 3, 5, 8, 12, 20, 35, 0}
+
+	isolatedPawnMg, isolatedPawnEg = -10, -15
+	doubledPawnMg, doubledPawnEg   = -10, -20
+	backwardPawnMg, backwardPawnEg = -8, -10
+)
+
+// Masks for finding pawn structures, by 64 based square
+var (
+	fileMasks      [8]uint64
+	rankMasks      [8]uint64
+	adjacentFiles  [8]uint64
+	forwardRanks   [2][8]uint64  // every rank ahead of a rank, for white/black
+	frontSpans     [2][64]uint64 // the squares ahead of a pawn on its file
+	passedMasks    [2][64]uint64 // the squares enemy pawns could stop a pawn from
+	supportMasks   [2][64]uint64 // where friendly pawns could still defend a pawn
+	connectedMasks [2][64]uint64 // where friendly pawns defend, or stand beside, a pawn
+)
+
+func init() {
+	for i := 0; i < 8; i++ {
+		for j := 0; j < 8; j++ {
+			fileMasks[i] |= 1 << (j*8 + i)
+			rankMasks[i] |= 1 << (i*8 + j)
+		}
+	}
+	for file := 0; file < 8; file++ {
+		if file > 0 {
+			adjacentFiles[file] |= fileMasks[file-1]
+		}
+		if file < 7 {
+			adjacentFiles[file] |= fileMasks[file+1]
+		}
+	}
+	for rank := 0; rank < 8; rank++ {
+		for ahead := rank + 1; ahead < 8; ahead++ {
+			forwardRanks[position.WHITE][rank] |= rankMasks[ahead]
+		}
+		for ahead := rank - 1; ahead >= 0; ahead-- {
+			forwardRanks[position.BLACK][rank] |= rankMasks[ahead]
+		}
+	}
+
+	for color := position.WHITE; color <= position.BLACK; color++ {
+		for sq := 0; sq < 64; sq++ {
+			file, rank := sq%8, sq/8
+			forward := forwardRanks[color][rank]
+
+			frontSpans[color][sq] = fileMasks[file] & forward
+			passedMasks[color][sq] = (fileMasks[file] | adjacentFiles[file]) & forward
+			supportMasks[color][sq] = adjacentFiles[file] &^ forward
+
+			behind := rank - 1
+			if color == position.BLACK {
+				behind = rank + 1
+			}
+			connectedMasks[color][sq] = adjacentFiles[file] & rankMasks[rank]
+			if behind >= 0 && behind < 8 {
+				connectedMasks[color][sq] |= adjacentFiles[file] & rankMasks[behind]
+			}
+		}
+	}
+}
+
+// relativeRank is the rank from the side's own end of the board
+func relativeRank(color, sq int) int {
+	if color == position.WHITE {
+		return sq / 8
+	}
+	return 7 - sq/8
+}
+
+// pawnAttacks are the squares the pawns of a side attack
+func pawnAttacks(color int, pawns uint64) uint64 {
+	notA, notH := ^fileMasks[0], ^fileMasks[7]
+	if color == position.WHITE {
+		return (pawns&notA)<<7 | (pawns&notH)<<9
+	}
+	return (pawns&notA)>>9 | (pawns&notH)>>7
+}
+
+/*
+pawnStructure scores each side's pawns. It only looks at the pawns, so
+the result can be cached by the pawn key, see PawnTable. Passed pawns
+are returned too, since how far they can get depends on the pieces.
+
+  - passed pawns have no enemy pawns in front of them, or on either side
+    that could take them, so nothing but pieces can stop them queening
+  - isolated pawns have no friendly pawns on either side to defend them
+  - doubled pawns are stuck behind another pawn of the same side
+  - backward pawns have been left behind by the pawns beside them, and
+    can't move up without being taken
+  - connected pawns stand beside or defend each other
+*/
+func pawnStructure(params *Params, pawns [3]uint64) (scores [2]TermScore, passed uint64) {
+	for color := position.WHITE; color <= position.BLACK; color++ {
+		own, enemy := pawns[color], pawns[color^1]
+		enemyAttacks := pawnAttacks(color^1, enemy)
+
+		score := &scores[color]
+		for bb := own; bb != 0; bb &= bb - 1 {
+			sq := bits.TrailingZeros64(bb)
+			file, rank := sq%8, relativeRank(color, sq)
+
+			if passedMasks[color][sq]&enemy == 0 {
+				passed |= 1 << sq
+				score.Mg += params.PassedPawnMg[rank]
+				score.Eg += params.PassedPawnEg[rank]
+			}
+
+			isolated := adjacentFiles[file]&own == 0
+			if isolated {
+				score.Mg += params.IsolatedPawnMg
+				score.Eg += params.IsolatedPawnEg
+			}
+
+			if frontSpans[color][sq]&own != 0 {
+				score.Mg += params.DoubledPawnMg
+				score.Eg += params.DoubledPawnEg
+			}
+
+			stop := sq + 8
+			if color == position.BLACK {
+				stop = sq - 8
+			}
+			if !isolated && supportMasks[color][sq]&own == 0 && enemyAttacks&(1<<stop) != 0 {
+				score.Mg += params.BackwardPawnMg
+				score.Eg += params.BackwardPawnEg
+			}
+
+			if connectedMasks[color][sq]&own != 0 {
+				score.Mg += params.ConnectedPawn[rank]
+				score.Eg += params.ConnectedPawn[rank]
+			}
+		}
+	}
+	return scores, passed
+}
+
+// passedPawnsFree scores each side's passed pawns with nothing in the way
+// of promoting. Unlike the rest of the pawn structure this depends on the
+// pieces too, so it's never cached.
+func passedPawnsFree(params *Params, p *position.Position, pawns [3]uint64, passed uint64) (eg [2]int) {
+	for color := position.WHITE; color <= position.BLACK; color++ {
+		for bb := passed & pawns[color]; bb != 0; bb &= bb - 1 {
+			sq := bits.TrailingZeros64(bb)
+			if pathClear(p, frontSpans[color][sq]) {
+				eg[color] += params.PassedPawnFreeEg[relativeRank(color, sq)]
+			}
+		}
+	}
+	return eg
+}
+
+// pathClear is if every square in a mask is empty
+func pathClear(p *position.Position, path uint64) bool {
+	for bb := path; bb != 0; bb &= bb - 1 {
+		if p.GetPiece(position.SQ120(bits.TrailingZeros64(bb))) != position.EMPTY {
+			return false
+		}
+	}
+	return true
+}
+
+// evaluatePawns scores the pawn structure, using
+// the pawn table when there is one
+func (s PositionEvaluator) evaluatePawns(p *position.Position, e *evaluation) {
+	pawns := p.GetPawns()
+
+	var scores [2]TermScore
+	var passed uint64
+	if s.pawns == nil {
+		scores, passed = pawnStructure(e.params, pawns)
+	} else if entry, ok := s.pawns.probe(p.GetPawnKey()); ok {
+		scores, passed = entry.scores, entry.passed
+	} else {
+		scores, passed = pawnStructure(e.params, pawns)
+		s.pawns.store(pawnEntry{key: p.GetPawnKey(), scores: scores, passed: passed})
+	}
+
+	free := passedPawnsFree(e.params, p, pawns, passed)
+	for color := position.WHITE; color <= position.BLACK; color++ {
+		e.add(TermPawns, color, scores[color].Mg, scores[color].Eg+free[color])
+	}
+}
@@ -0,0 +1,113 @@
+package eval
+
+import (
+	"cacti-chess/engine/position"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_pawnStructure(t *testing.T) {
+	type testCase struct {
+		name       string
+		fen        string
+		want       [2]TermScore // white/black
+		wantPassed uint64
+	}
+
+	for _, tc := range []testCase{
+		{
+			"no pawns",
+			"4k3/8/8/8/8/8/8/4K3 w - - 0 1",
+			[2]TermScore{}, 0,
+		},
+		{
+			"starting position",
+			"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			[2]TermScore{{8 * connectedPawn[1], 8 * connectedPawn[1]}, {8 * connectedPawn[1], 8 * connectedPawn[1]}}, 0,
+		},
+		{
+			"isolated passed pawn",
+			"4k3/8/8/8/8/8/4P3/4K3 w - - 0 1",
+			[2]TermScore{{passedPawnMg[1] + isolatedPawnMg, passedPawnEg[1] + isolatedPawnEg}},
+			1 << 12,
+		},
+		{
+			"black passed pawn on the 7th",
+			"4k3/8/8/8/8/8/4p3/K7 w - - 0 1",
+			[2]TermScore{{}, {passedPawnMg[6] + isolatedPawnMg, passedPawnEg[6] + isolatedPawnEg}},
+			1 << 12,
+		},
+		{
+			"doubled pawns",
+			"4k3/8/8/8/8/4P3/4P3/4K3 w - - 0 1",
+			[2]TermScore{{
+				passedPawnMg[1] + passedPawnMg[2] + 2*isolatedPawnMg + doubledPawnMg,
+				passedPawnEg[1] + passedPawnEg[2] + 2*isolatedPawnEg + doubledPawnEg,
+			}},
+			1<<12 | 1<<20,
+		},
+		{
+			"connected pawns against an isolated pawn",
+			"4k3/8/8/4p3/3PP3/8/8/4K3 w - - 0 1",
+			[2]TermScore{{2 * connectedPawn[3], 2 * connectedPawn[3]}, {isolatedPawnMg, isolatedPawnEg}},
+			0,
+		},
+		{
+			"backward pawn",
+			"4k3/8/8/4p3/4P3/3P4/8/4K3 w - - 0 1",
+			[2]TermScore{{backwardPawnMg + connectedPawn[3], backwardPawnEg + connectedPawn[3]}, {isolatedPawnMg, isolatedPawnEg}},
+			0,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := position.FromFen(tc.fen)
+			require.Nil(t, err)
+
+			scores, passed := pawnStructure(&defaultParams, p.GetPawns())
+			assert.Equal(t, tc.want, scores)
+			assert.Equal(t, tc.wantPassed, passed)
+		})
+	}
+}
+
+func Test_passedPawnsFree(t *testing.T) {
+	free := func(fen string) [2]int {
+		p, err := position.FromFen(fen)
+		require.Nil(t, err)
+		pawns := p.GetPawns()
+		_, passed := pawnStructure(&defaultParams, pawns)
+		return passedPawnsFree(&defaultParams, p, pawns, passed)
+	}
+
+	t.Run("it scores a passed pawn with a clear path", func(t *testing.T) {
+		assert.Equal(t, [2]int{passedPawnFreeEg[5], 0}, free("k7/8/4P3/8/8/8/8/4K3 w - - 0 1"))
+		assert.Equal(t, [2]int{0, passedPawnFreeEg[5]}, free("4k3/8/8/8/8/4p3/8/K7 w - - 0 1"))
+	})
+
+	t.Run("it doesn't score a blockaded passed pawn", func(t *testing.T) {
+		assert.Equal(t, [2]int{}, free("4k3/8/4P3/8/8/8/8/4K3 w - - 0 1"))
+		assert.Equal(t, [2]int{}, free("k3n3/8/4P3/8/8/8/8/4K3 w - - 0 1"))
+	})
+}
+
+func TestPositionEvaluator_pawnTable(t *testing.T) {
+	cached := NewPositionEvaluator()
+	uncached := PositionEvaluator{}
+
+	p, err := position.FromFen("r1bqkbnr/pp1ppppp/2n5/2p5/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3")
+	require.Nil(t, err)
+
+	for _, mv := range []string{"d2d4", "c5d4", "f3d4", "c6d4", "d1d4", "d7d6"} {
+		key, err := p.ParseMove(mv)
+		require.Nil(t, err)
+		require.True(t, p.MakeMove(key))
+
+		// twice, so the second is read from the table
+		assert.Equal(t, uncached.Evaluate(p), cached.Evaluate(p), mv)
+		assert.Equal(t, uncached.Evaluate(p), cached.Evaluate(p), mv)
+
+		_, hit := cached.pawns.probe(p.GetPawnKey())
+		assert.True(t, hit, mv)
+	}
+}
@@ -1,15 +1,21 @@
 package eval
 
+import "cacti-chess/engine/position"
+
 // Piece squares give a simple way to evaluate position for pieces.
 // For example, pieces are generally better towards the center,
 // pieces should get moved from start, etc.
 
+// Each piece has a middlegame (Mg) and endgame (Eg) table, which are
+// blended by the game phase, see Evaluate. These are the built-in
+// tables, see Params.
+
 // These are all from white's perspective, so
 // index[0] == A1 etc. Flip them with the
-// mirror64 board i.e. pawnTable[mirror64[0]]
+// mirror64 board i.e. pawnTableMg[mirror64[0]]
 
 var (
-	pawnTable = [64]int{
+	pawnTableMg = [64]int{
 		0, 0, 0, 0, 0, 0, 0, 0,
 		10, 10, 0, -10, -10, 0, 10, 10,
 		5, 0, 0, 5, 5, 0, 0, 5,
@@ -19,7 +25,7 @@ var (
 		20, 20, 20, 30, 30, 20, 20, 20,
 		0, 0, 0, 0, 0, 0, 0, 0,
 	}
-	knightTable = [64]int{
+	knightTableMg = [64]int{
 		0, -10, 0, 0, 0, 0, -10, 0,
 		0, 0, 0, 5, 5, 0, 0, 0,
 		0, 0, 10, 10, 10, 10, 0, 0,
@@ -29,7 +35,7 @@ var (
 		0, 0, 5, 10, 10, 5, 0, 0,
 		0, 0, 0, 0, 0, 0, 0, 0,
 	}
-	bishopTable = [64]int{
+	bishopTableMg = [64]int{
 		0, 0, -10, 0, 0, -10, 0, 0,
 		0, 0, 0, 10, 10, 0, 0, 0,
 		0, 0, 10, 15, 15, 10, 0, 0,
@@ -39,7 +45,7 @@ var (
 		0, 0, 0, 10, 10, 0, 0, 0,
 		0, 0, 0, 0, 0, 0, 0, 0,
 	}
-	rookTable = [64]int{
+	rookTableMg = [64]int{
 		0, 0, 5, 10, 10, 5, 0, 0,
 		0, 0, 5, 10, 10, 5, 0, 0,
 		0, 0, 5, 10, 10, 5, 0, 0,
@@ -49,6 +55,89 @@ var (
 		25, 25, 25, 25, 25, 25, 25, 25,
 		0, 0, 5, 10, 10, 5, 0, 0,
 	}
+	queenTableMg = [64]int{
+		-5, -5, -5, 0, 0, -5, -5, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 5, 5, 5, 5, 0, -5,
+		0, 0, 5, 5, 5, 5, 0, 0,
+		0, 0, 5, 5, 5, 5, 0, 0,
+		-5, 0, 5, 5, 5, 5, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-10, -5, -5, -5, -5, -5, -5, -10,
+	}
+	kingTableMg = [64]int{
+		20, 30, 10, 0, 0, 10, 30, 20,
+		20, 20, 0, 0, 0, 0, 20, 20,
+		-10, -20, -20, -20, -20, -20, -20, -10,
+		-20, -30, -30, -40, -40, -30, -30, -20,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+	}
+
+	// endgames are about pushing pawns and bringing the king
+	// into play, while the other pieces just want to be central
+	pawnTableEg = [64]int{
+		0, 0, 0, 0, 0, 0, 0, 0,
+		5, 5, 5, 5, 5, 5, 5, 5,
+		10, 10, 10, 10, 10, 10, 10, 10,
+		20, 20, 20, 20, 20, 20, 20, 20,
+		35, 35, 35, 35, 35, 35, 35, 35,
+		55, 55, 55, 55, 55, 55, 55, 55,
+		80, 80, 80, 80, 80, 80, 80, 80,
+		0, 0, 0, 0, 0, 0, 0, 0,
+	}
+	knightTableEg = [64]int{
+		-20, -10, -5, -5, -5, -5, -10, -20,
+		-10, 0, 0, 5, 5, 0, 0, -10,
+		-5, 0, 10, 10, 10, 10, 0, -5,
+		-5, 5, 10, 15, 15, 10, 5, -5,
+		-5, 5, 10, 15, 15, 10, 5, -5,
+		-5, 0, 10, 10, 10, 10, 0, -5,
+		-10, 0, 0, 5, 5, 0, 0, -10,
+		-20, -10, -5, -5, -5, -5, -10, -20,
+	}
+	bishopTableEg = [64]int{
+		-10, -5, -5, -5, -5, -5, -5, -10,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 5, 5, 5, 5, 0, -5,
+		-5, 0, 5, 10, 10, 5, 0, -5,
+		-5, 0, 5, 10, 10, 5, 0, -5,
+		-5, 0, 5, 5, 5, 5, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-10, -5, -5, -5, -5, -5, -5, -10,
+	}
+	rookTableEg = [64]int{
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		10, 10, 10, 10, 10, 10, 10, 10,
+		0, 0, 0, 0, 0, 0, 0, 0,
+	}
+	queenTableEg = [64]int{
+		-10, -5, -5, -5, -5, -5, -5, -10,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 5, 10, 10, 5, 0, -5,
+		-5, 0, 10, 15, 15, 10, 0, -5,
+		-5, 0, 10, 15, 15, 10, 0, -5,
+		-5, 0, 5, 10, 10, 5, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-10, -5, -5, -5, -5, -5, -5, -10,
+	}
+	kingTableEg = [64]int{
+		-50, -30, -30, -30, -30, -30, -30, -50,
+		-30, -20, -10, -10, -10, -10, -20, -30,
+		-30, -10, 10, 20, 20, 10, -10, -30,
+		-30, -10, 20, 30, 30, 20, -10, -30,
+		-30, -10, 20, 30, 30, 20, -10, -30,
+		-30, -10, 10, 20, 20, 10, -10, -30,
+		-30, -20, -10, 0, 0, -10, -20, -30,
+		-50, -40, -30, -20, -20, -30, -40, -50,
+	}
 	mirror64 = [64]int{
 		56, 57, 58, 59, 60, 61, 62, 63,
 		48, 49, 50, 51, 52, 53, 54, 55,
@@ -60,3 +149,14 @@ var (
 		0, 1, 2, 3, 4, 5, 6, 7,
 	}
 )
+
+// pieceTypes are the white/black pieces of each type, in the order
+// Params keeps the weights for them
+var pieceTypes = [6][2]position.Piece{
+	{position.PwP, position.PbP},
+	{position.PwN, position.PbN},
+	{position.PwB, position.PbB},
+	{position.PwR, position.PbR},
+	{position.PwQ, position.PbQ},
+	{position.PwK, position.PbK},
+}
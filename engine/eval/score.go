@@ -0,0 +1,54 @@
+package eval
+
+/*
+Score is an evaluation in centipawns. Scores are whole numbers so they
+compare exactly, which keeps searches reproducible and lets them be packed
+into the transposition table as is.
+
+Mates are scored relative to Mate, with longer mates scoring less so the
+search prefers the shortest one. Every mate score is within MaxPly of Mate,
+far outside anything the evaluation returns.
+*/
+type Score int32
+
+const (
+	// Mate is the score for delivering mate on the board right now
+	Mate Score = 29000
+
+	// Infinity is beyond any score, for the bounds of a full window
+	Infinity Score = 30000
+
+	// MaxPly is the furthest from the root a mate can be scored
+	MaxPly = 128
+)
+
+// MateIn is the score for delivering mate in a number of plies
+func MateIn(ply int) Score {
+	return Mate - Score(ply)
+}
+
+// MatedIn is the score for being mated in a number of plies
+func MatedIn(ply int) Score {
+	return -Mate + Score(ply)
+}
+
+// IsMate is if the score is a forced mate, for either side
+func (s Score) IsMate() bool {
+	return s >= MateIn(MaxPly) || s <= MatedIn(MaxPly)
+}
+
+// MateMoves converts a mate score into full moves until mate, negative
+// when getting mated, and ok is false for any other score. Mate in 1 is a
+// single ply away, while being mated in 1 is two plies away. Being mated
+// on the board right now is mate in 0.
+func (s Score) MateMoves() (moves int, ok bool) {
+	switch {
+	case s >= MateIn(MaxPly):
+		plies := int(Mate - s)
+		return (plies + 1) / 2, true
+	case s <= MatedIn(MaxPly):
+		plies := int(Mate + s)
+		return -plies / 2, true
+	}
+	return 0, false
+}
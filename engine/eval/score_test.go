@@ -0,0 +1,39 @@
+package eval
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestScore_MateMoves(t *testing.T) {
+	type testCase struct {
+		name   string
+		score  Score
+		mate   bool
+		expect int
+	}
+
+	for _, tc := range []testCase{
+		{"a normal score", 250, false, 0},
+		{"a losing score", -900, false, 0},
+		{"mate in 1", MateIn(1), true, 1},
+		{"mate in 2", MateIn(3), true, 2},
+		{"mated in 1", MatedIn(2), true, -1},
+		{"mated in 2", MatedIn(4), true, -2},
+		{"mated on the board", MatedIn(0), true, 0},
+		{"the longest mate", MateIn(MaxPly), true, MaxPly / 2},
+		{"just past the longest mate", MateIn(MaxPly + 1), false, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.mate, tc.score.IsMate())
+			moves, ok := tc.score.MateMoves()
+			assert.Equal(t, tc.mate, ok)
+			assert.Equal(t, tc.expect, moves)
+		})
+	}
+
+	t.Run("mates are inside the window", func(t *testing.T) {
+		assert.Less(t, Mate, Infinity)
+		assert.Equal(t, -MateIn(5), MatedIn(5))
+	})
+}
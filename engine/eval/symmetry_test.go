@@ -0,0 +1,118 @@
+package eval
+
+import (
+	"bufio"
+	"cacti-chess/engine/position"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"strings"
+	"testing"
+)
+
+// readSymmetryFens reads the positions checked for symmetry, which are
+// every perft test case plus a few middlegames where all the pieces are
+// still fighting. Both files have a fen without move counters to start
+// each line.
+func readSymmetryFens(t *testing.T) []string {
+	t.Helper()
+
+	fens := []string{}
+	for _, path := range []string{"../perft/perft_test_cases.txt", "symmetry_test_cases.txt"} {
+		file, err := os.Open(path)
+		require.Nil(t, err)
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			fen := strings.TrimSpace(strings.Split(scanner.Text(), ",")[0])
+			if fen != "" {
+				fens = append(fens, fen+" 0 1")
+			}
+		}
+		require.Nil(t, scanner.Err())
+		file.Close()
+	}
+	return fens
+}
+
+// symmetricTerms are the terms that should be the same for a position
+// mirrored left to right. Only some piece square tables are the same
+// either side of the board, so the rest aren't included.
+func symmetricTerms(params *Params) []Term {
+	terms := []Term{}
+	for term := Term(0); term < TermCount; term++ {
+		if term >= TermPawnSquares && term <= TermKingSquares {
+			i := term - TermPawnSquares
+			if !symmetricTable(&params.PieceSquareMg[i]) || !symmetricTable(&params.PieceSquareEg[i]) {
+				continue
+			}
+		}
+		terms = append(terms, term)
+	}
+	return terms
+}
+
+func symmetricTable(table *[64]int) bool {
+	for sq := range table {
+		if table[sq] != table[sq^7] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPositionEvaluator_symmetry(t *testing.T) {
+	fens := readSymmetryFens(t)
+	require.Greater(t, len(fens), 1000)
+
+	scr := PositionEvaluator{}
+	cached := NewPositionEvaluator()
+	mirrorTerms := symmetricTerms(&defaultParams)
+
+	failures := 0
+	for _, fen := range fens {
+		p, err := position.FromFen(fen)
+		require.Nil(t, err, fen)
+		trace := scr.Trace(p)
+
+		ok := t.Run(fen, func(t *testing.T) {
+			// the colors swapped is the same position for the other side
+			flipped := scr.Trace(p.Flip())
+			assert.Equal(t, trace.Score, -flipped.Score)
+			for term := Term(0); term < TermCount; term++ {
+				assert.Equal(t, trace.Terms[term][position.WHITE], flipped.Terms[term][position.BLACK], term.String())
+				assert.Equal(t, trace.Terms[term][position.BLACK], flipped.Terms[term][position.WHITE], term.String())
+			}
+
+			// either side of the board
+			mirrored := scr.Trace(p.Mirror())
+			for _, term := range mirrorTerms {
+				assert.Equal(t, trace.Terms[term], mirrored.Terms[term], term.String())
+			}
+
+			// the same however it's worked out
+			assert.Equal(t, trace.Score, scr.Evaluate(p))
+			assert.Equal(t, trace.Score, cached.Evaluate(p))
+			assert.Equal(t, trace.Score, cached.Evaluate(p))
+		})
+
+		// one broken term fails nearly every position, so stop
+		// before there's too much output to find the first
+		if !ok {
+			failures++
+			if failures >= 10 {
+				t.FailNow()
+			}
+		}
+	}
+}
+
+func Test_symmetricTerms(t *testing.T) {
+	params := DefaultParams()
+	terms := symmetricTerms(&params)
+	assert.Contains(t, terms, TermMaterial)
+	assert.Contains(t, terms, TermMobility)
+
+	params.PieceSquareEg[1][0] = 1
+	assert.NotContains(t, symmetricTerms(&params), TermKnightSquares)
+}
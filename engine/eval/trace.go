@@ -0,0 +1,151 @@
+package eval
+
+import (
+	"cacti-chess/engine/position"
+	"fmt"
+	"strings"
+)
+
+// Term is one part of the evaluation, as broken down by a Trace
+type Term int
+
+const (
+	TermMaterial Term = iota
+
+	// piece squares are in the same order as pieceTypes,
+	// so TermPawnSquares+i is the table of pieceTypes[i]
+	TermPawnSquares
+	TermKnightSquares
+	TermBishopSquares
+	TermRookSquares
+	TermQueenSquares
+	TermKingSquares
+	TermPawns
+	TermKingShield
+	TermKingStorm
+	TermKingFiles
+	TermKingAttack
+	TermMobility
+	TermCount
+)
+
+var termNames = [TermCount]string{
+	TermMaterial:      "Material",
+	TermPawnSquares:   "Pawn squares",
+	TermKnightSquares: "Knight squares",
+	TermBishopSquares: "Bishop squares",
+	TermRookSquares:   "Rook squares",
+	TermQueenSquares:  "Queen squares",
+	TermKingSquares:   "King squares",
+	TermPawns:         "Pawns",
+	TermKingShield:    "King shield",
+	TermKingStorm:     "Pawn storm",
+	TermKingFiles:     "King files",
+	TermKingAttack:    "King attack",
+	TermMobility:      "Mobility",
+}
+
+func (t Term) String() string {
+	return termNames[t]
+}
+
+// TermScore is a term's middlegame and endgame scores
+type TermScore struct {
+	Mg, Eg int
+}
+
+// Trace is the evaluation of a position broken down by term, with
+// each side's score for the term from its own side
+type Trace struct {
+	Terms [TermCount][2]TermScore // by white/black
+	Phase int
+	Score Score // from white's side, the same as Evaluate
+}
+
+// Trace evaluates a position like Evaluate, keeping every term's score
+func (s PositionEvaluator) Trace(p *position.Position) Trace {
+	trace := Trace{}
+	trace.Score = s.evaluate(p, &trace)
+	return trace
+}
+
+// Total is a term's score from white's side, before tapering
+func (t *Trace) Total(term Term) TermScore {
+	white, black := t.Terms[term][position.WHITE], t.Terms[term][position.BLACK]
+	return TermScore{white.Mg - black.Mg, white.Eg - black.Eg}
+}
+
+// Tapered is a term's total blended by the game phase, like Evaluate
+// does, which is how much it's worth in the final score
+func (t *Trace) Tapered(term Term) int {
+	return t.taper(t.Total(term))
+}
+
+func (t *Trace) taper(s TermScore) int {
+	return (s.Mg*t.Phase + s.Eg*(PhaseMax-t.Phase)) / PhaseMax
+}
+
+/*
+String writes the trace as a table, with the middlegame and endgame scores
+of each side and the total from white's side, i.e.
+
+	Term           |    White    |    Black    |    Total    | Tapered
+	               |    MG    EG |    MG    EG |    MG    EG |
+	---------------+-------------+-------------+-------------+--------
+	Material       |  4210  4210 |  4210  4210 |     0     0 |       0
+	...
+
+The tapered terms can add up to slightly less than the score, since each
+is rounded on its own.
+*/
+func (t Trace) String() string {
+	b := strings.Builder{}
+	row := func(name string, white, black, total TermScore, tapered int) {
+		b.WriteString(fmt.Sprintf("%-14s | %5d %5d | %5d %5d | %5d %5d | %7d\n",
+			name, white.Mg, white.Eg, black.Mg, black.Eg, total.Mg, total.Eg, tapered))
+	}
+	sum := func(a, b TermScore) TermScore {
+		return TermScore{a.Mg + b.Mg, a.Eg + b.Eg}
+	}
+	line := "---------------+-------------+-------------+-------------+--------\n"
+
+	b.WriteString(fmt.Sprintf("%-14s | %11s | %11s | %11s | %7s\n", "Term", "White", "Black", "Total", "Tapered"))
+	b.WriteString(fmt.Sprintf("%-14s | %5s %5s | %5s %5s | %5s %5s |\n", "", "MG", "EG", "MG", "EG", "MG", "EG"))
+	b.WriteString(line)
+
+	var white, black, total TermScore
+	for term := Term(0); term < TermCount; term++ {
+		row(term.String(), t.Terms[term][position.WHITE], t.Terms[term][position.BLACK], t.Total(term), t.Tapered(term))
+		white = sum(white, t.Terms[term][position.WHITE])
+		black = sum(black, t.Terms[term][position.BLACK])
+		total = sum(total, t.Total(term))
+	}
+	b.WriteString(line)
+	row("Total", white, black, total, t.taper(total))
+
+	b.WriteString(fmt.Sprintf("\nPhase: %d/%d (%d%% middlegame)\n", t.Phase, PhaseMax, t.Phase*100/PhaseMax))
+	b.WriteString(fmt.Sprintf("Score: %d (white's side)\n", t.Score))
+	return b.String()
+}
+
+// evaluation adds up the terms of an evaluation, from white's
+// side, and keeps them in a trace when there is one
+type evaluation struct {
+	mg, eg int
+	params *Params
+	trace  *Trace
+}
+
+func (e *evaluation) add(term Term, color, mg, eg int) {
+	if color == position.WHITE {
+		e.mg += mg
+		e.eg += eg
+	} else {
+		e.mg -= mg
+		e.eg -= eg
+	}
+	if e.trace != nil {
+		e.trace.Terms[term][color].Mg += mg
+		e.trace.Terms[term][color].Eg += eg
+	}
+}
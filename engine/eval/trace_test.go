@@ -0,0 +1,66 @@
+package eval
+
+import (
+	"cacti-chess/engine/position"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestPositionEvaluator_Trace(t *testing.T) {
+	fens := []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		"r1bq1rk1/pp2ppbp/2np1np1/8/3NP3/2N1BP2/PPPQ2PP/R3KB1R w KQ - 0 1",
+	}
+
+	for _, fen := range fens {
+		t.Run(fen, func(t *testing.T) {
+			p, err := position.FromFen(fen)
+			require.Nil(t, err)
+
+			scr := NewPositionEvaluator()
+			trace := scr.Trace(p)
+			assert.Equal(t, scr.Evaluate(p), trace.Score)
+			assert.Equal(t, Phase(p), trace.Phase)
+
+			// the terms add up to the score
+			mg, eg := 0, 0
+			for term := Term(0); term < TermCount; term++ {
+				mg += trace.Total(term).Mg
+				eg += trace.Total(term).Eg
+			}
+			assert.Equal(t, trace.Score, Score((mg*trace.Phase+eg*(PhaseMax-trace.Phase))/PhaseMax))
+
+			out := trace.String()
+			for term := Term(0); term < TermCount; term++ {
+				assert.Contains(t, out, term.String())
+			}
+			assert.Contains(t, out, fmt.Sprintf("Score: %d", trace.Score))
+		})
+	}
+}
+
+func TestTrace_String(t *testing.T) {
+	p, err := position.FromFen("4k3/8/8/8/8/8/4P3/4K3 w - - 0 1")
+	require.Nil(t, err)
+	trace := PositionEvaluator{}.Trace(p)
+
+	t.Run("it splits each term by side", func(t *testing.T) {
+		assert.Contains(t, trace.String(), "Material       |   100   100 |     0     0 |   100   100 |     100\n")
+	})
+
+	t.Run("it gives a table for each piece", func(t *testing.T) {
+		king := trace.Terms[TermKingSquares]
+		assert.NotEqual(t, TermScore{}, king[position.WHITE])
+		assert.Equal(t, king[position.WHITE], king[position.BLACK])
+		assert.Equal(t, TermScore{}, trace.Terms[TermQueenSquares][position.WHITE])
+	})
+
+	t.Run("it gives the phase", func(t *testing.T) {
+		assert.Equal(t, 0, trace.Phase)
+		assert.Contains(t, trace.String(), "Phase: 0/256 (0% middlegame)\n")
+	})
+}
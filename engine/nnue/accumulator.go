@@ -0,0 +1,125 @@
+package nnue
+
+import "cacti-chess/engine/position"
+
+/*
+Accumulator holds the first layer of the network for each side, for the
+position it's attached to. It watches the position's pieces being added,
+cleared and moved, adding and taking away the weights of the features that
+change, so making a move costs a few columns of weights rather than the
+whole board.
+
+When a king moves, every feature from that side's view changes, so its
+half is only marked as stale, and worked out again from the board the
+next time the position is evaluated.
+*/
+type Accumulator struct {
+	net    *Network
+	pos    *position.Position
+	values [2][]int32
+	stale  [2]bool
+}
+
+// newAccumulator creates an accumulator for a position,
+// which still needs attaching with SetObserver
+func newAccumulator(net *Network, p *position.Position) *Accumulator {
+	a := &Accumulator{net: net, pos: p}
+	for color := range a.values {
+		a.values[color] = make([]int32, net.hidden)
+		a.refresh(color)
+	}
+	return a
+}
+
+// refresh works out a side's half again from the board
+func (a *Accumulator) refresh(color int) {
+	values := a.values[color]
+	for i, bias := range a.net.featureBiases {
+		values[i] = int32(bias)
+	}
+
+	kingSq := position.SQ64(a.pos.GetKingSq(color))
+	for sq := 0; sq < 64; sq++ {
+		pce := a.pos.GetPiece(position.SQ120(sq))
+		if pce == position.EMPTY || isKing(pce) {
+			continue
+		}
+		a.add(values, featureIndex(color, kingSq, pce, sq))
+	}
+	a.stale[color] = false
+}
+
+// update brings both halves up to date
+func (a *Accumulator) update() {
+	for color, stale := range a.stale {
+		if stale {
+			a.refresh(color)
+		}
+	}
+}
+
+func (a *Accumulator) add(values []int32, feature int) {
+	weights := a.net.featureWeights[feature*a.net.hidden : (feature+1)*a.net.hidden]
+	for i, w := range weights {
+		values[i] += int32(w)
+	}
+}
+
+func (a *Accumulator) sub(values []int32, feature int) {
+	weights := a.net.featureWeights[feature*a.net.hidden : (feature+1)*a.net.hidden]
+	for i, w := range weights {
+		values[i] -= int32(w)
+	}
+}
+
+// PieceAdded adds a piece's features
+func (a *Accumulator) PieceAdded(pce position.Piece, sq int) {
+	if isKing(pce) {
+		a.stale[pieceColor(pce)] = true
+		return
+	}
+	for color := range a.values {
+		if !a.stale[color] {
+			a.add(a.values[color], featureIndex(color, position.SQ64(a.pos.GetKingSq(color)), pce, position.SQ64(sq)))
+		}
+	}
+}
+
+// PieceCleared takes away a piece's features
+func (a *Accumulator) PieceCleared(pce position.Piece, sq int) {
+	if isKing(pce) {
+		a.stale[pieceColor(pce)] = true
+		return
+	}
+	for color := range a.values {
+		if !a.stale[color] {
+			a.sub(a.values[color], featureIndex(color, position.SQ64(a.pos.GetKingSq(color)), pce, position.SQ64(sq)))
+		}
+	}
+}
+
+// PieceMoved swaps a piece's features for the ones on its new square
+func (a *Accumulator) PieceMoved(pce position.Piece, from, to int) {
+	if isKing(pce) {
+		a.stale[pieceColor(pce)] = true
+		return
+	}
+	for color := range a.values {
+		if !a.stale[color] {
+			kingSq := position.SQ64(a.pos.GetKingSq(color))
+			a.sub(a.values[color], featureIndex(color, kingSq, pce, position.SQ64(from)))
+			a.add(a.values[color], featureIndex(color, kingSq, pce, position.SQ64(to)))
+		}
+	}
+}
+
+func isKing(pce position.Piece) bool {
+	return pce == position.PwK || pce == position.PbK
+}
+
+func pieceColor(pce position.Piece) int {
+	if pce >= position.PbP {
+		return position.BLACK
+	}
+	return position.WHITE
+}
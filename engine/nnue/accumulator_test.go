@@ -0,0 +1,80 @@
+package nnue
+
+import (
+	"cacti-chess/engine/position"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/rand"
+	"testing"
+)
+
+// assertRefreshed checks the accumulator matches one worked out from scratch
+func assertRefreshed(t *testing.T, a *Accumulator, p *position.Position, msg string) {
+	t.Helper()
+	a.update()
+	fresh := newAccumulator(a.net, p)
+	assert.Equal(t, fresh.values, a.values, msg)
+}
+
+func TestAccumulator(t *testing.T) {
+	net := randomNetwork(3, 8)
+
+	fens := []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"r3k2r/1P4p1/8/3pP3/8/8/6p1/R3K2R w KQkq d6 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+	}
+	for _, fen := range fens {
+		t.Run(fen, func(t *testing.T) {
+			p, err := position.FromFen(fen)
+			require.Nil(t, err)
+
+			e := NewEvaluator(net)
+			e.Evaluate(p)
+			a, ok := p.GetObserver().(*Accumulator)
+			require.True(t, ok)
+
+			// play out random games, checking the accumulator after every move
+			rng := rand.New(rand.NewSource(1))
+			made := 0
+			for ply := 0; ply < 60; ply++ {
+				moves := *p.GenerateLegalMoves()
+				if len(moves) == 0 {
+					break
+				}
+				move := moves[rng.Intn(len(moves))].Key
+				require.True(t, p.MakeMove(move))
+				made++
+				assertRefreshed(t, a, p, p.ToFen())
+			}
+
+			for ; made > 0; made-- {
+				p.UndoMove()
+				assertRefreshed(t, a, p, p.ToFen())
+			}
+			assert.Equal(t, fen, p.ToFen())
+		})
+	}
+}
+
+func TestAccumulator_KingMoves(t *testing.T) {
+	p, err := position.FromFen("r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1")
+	require.Nil(t, err)
+
+	a := newAccumulator(randomNetwork(4, 4), p)
+	p.SetObserver(a)
+
+	// castling moves the rook before the king, which must leave
+	// white's half to be refreshed, but keep black's up to date
+	move, err := p.ParseMove("e1g1")
+	require.Nil(t, err)
+	require.True(t, p.MakeMove(move))
+	assert.Equal(t, [2]bool{true, false}, a.stale)
+	assertRefreshed(t, a, p, "after castling")
+	assert.Equal(t, [2]bool{false, false}, a.stale)
+
+	p.UndoMove()
+	assert.Equal(t, [2]bool{true, false}, a.stale)
+	assertRefreshed(t, a, p, "after undoing castling")
+}
@@ -0,0 +1,62 @@
+package nnue
+
+import (
+	"cacti-chess/engine/eval"
+	"cacti-chess/engine/position"
+)
+
+/*
+Evaluator scores positions with a network, and can be used by the search
+in place of eval.PositionEvaluator.
+
+The first time it sees a position, it attaches an Accumulator to it, which
+keeps up with the moves made from then on. Each search thread evaluates
+its own copy of the position, so nothing is shared between them except
+the network, which is only ever read.
+*/
+type Evaluator struct {
+	net *Network
+}
+
+// NewEvaluator creates an evaluator for a network
+func NewEvaluator(net *Network) *Evaluator {
+	return &Evaluator{net: net}
+}
+
+// Evaluate returns the score for white, so black is winning when it's negative
+func (e *Evaluator) Evaluate(p *position.Position) eval.Score {
+	if p.GetSide() == position.WHITE {
+		return e.EvaluateAbsolute(p)
+	}
+	return -e.EvaluateAbsolute(p)
+}
+
+// EvaluateAbsolute returns the score for the side to move
+func (e *Evaluator) EvaluateAbsolute(p *position.Position) eval.Score {
+	a := e.accumulator(p)
+	a.update()
+
+	side := p.GetSide()
+	score := eval.Score(e.net.output(a.values[side], a.values[side^1]))
+
+	// keep well clear of mate scores, whatever the weights
+	limit := eval.MateIn(eval.MaxPly) - 1
+	if score > limit {
+		return limit
+	}
+	if score < -limit {
+		return -limit
+	}
+	return score
+}
+
+// accumulator returns the accumulator attached to a position,
+// attaching a new one if it has none for this network
+func (e *Evaluator) accumulator(p *position.Position) *Accumulator {
+	if a, ok := p.GetObserver().(*Accumulator); ok && a.net == e.net && a.pos == p {
+		return a
+	}
+	a := newAccumulator(e.net, p)
+	p.SetObserver(a)
+	return a
+}
@@ -0,0 +1,76 @@
+package nnue
+
+import (
+	"cacti-chess/engine/eval"
+	"cacti-chess/engine/position"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestEvaluator_Evaluate(t *testing.T) {
+	// every neuron of the side to move is maxed out,
+	// and a full neuron times outputScale is evalScale
+	net := NewNetwork(2)
+	net.featureBiases = []int16{activationMax, -10}
+	net.outputWeights = []int16{outputScale, 100, 0, 100}
+	e := NewEvaluator(net)
+
+	white, err := position.FromFen("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+	require.Nil(t, err)
+	assert.Equal(t, eval.Score(evalScale), e.Evaluate(white))
+	assert.Equal(t, eval.Score(evalScale), e.EvaluateAbsolute(white))
+
+	black, err := position.FromFen("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1")
+	require.Nil(t, err)
+	assert.Equal(t, eval.Score(-evalScale), e.Evaluate(black))
+	assert.Equal(t, eval.Score(evalScale), e.EvaluateAbsolute(black))
+
+	// scores are kept out of the mate range
+	net.outputBias = 1 << 30
+	assert.Equal(t, eval.MateIn(eval.MaxPly)-1, e.Evaluate(white))
+	assert.Equal(t, -eval.MateIn(eval.MaxPly)+1, e.Evaluate(black))
+}
+
+func TestEvaluator_Flip(t *testing.T) {
+	e := NewEvaluator(randomNetwork(5, 16))
+
+	fens := []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"rnbqkb1r/pp1p1ppp/2p5/4P3/2B5/8/PPP1NnPP/RNBQK2R w KQkq - 0 6",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+	}
+	for _, fen := range fens {
+		t.Run(fen, func(t *testing.T) {
+			p, err := position.FromFen(fen)
+			require.Nil(t, err)
+
+			// each side sees its own pieces the same way, so
+			// swapping the colours only swaps the score's sign
+			assert.Equal(t, -e.Evaluate(p), e.Evaluate(p.Flip()))
+			assert.Equal(t, e.EvaluateAbsolute(p), e.EvaluateAbsolute(p.Flip()))
+		})
+	}
+}
+
+func TestEvaluator_Clone(t *testing.T) {
+	e := NewEvaluator(randomNetwork(6, 8))
+
+	p, err := position.FromFen("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	require.Nil(t, err)
+	score := e.Evaluate(p)
+	a := p.GetObserver()
+
+	// a clone gets an accumulator of its own, leaving the original's alone
+	c := p.Clone()
+	assert.Equal(t, score, e.Evaluate(c))
+	assert.NotSame(t, a, c.GetObserver())
+	assert.Same(t, a, p.GetObserver())
+
+	// as does a position evaluated by a different network
+	other := NewEvaluator(randomNetwork(7, 8))
+	other.Evaluate(p)
+	assert.NotSame(t, a, p.GetObserver())
+	assert.Equal(t, score, e.Evaluate(p))
+}
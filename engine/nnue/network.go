@@ -0,0 +1,156 @@
+package nnue
+
+import (
+	"bufio"
+	"cacti-chess/engine/position"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+/*
+Network is a small HalfKP network. Each side sees the board from its own
+king, with a feature for every other piece on every square, for every
+square its king could be on:
+
+	64 king squares × 10 pieces (pawn to queen, ours or theirs) × 64 squares
+
+Only about 30 features are ever active, and moving a piece only changes a
+couple of them, so the first layer is kept up to date a move at a time by
+an Accumulator rather than worked out again for each position.
+
+Both sides share the first layer's weights, and their halves are joined
+with the side to move first, through a clipped ReLU to the output. Weights
+are quantized to integers, so the output is scaled back to centipawns.
+*/
+type Network struct {
+	hidden int // neurons in each side's half of the first layer
+
+	featureWeights []int16 // hidden weights for each feature, one after another
+	featureBiases  []int16
+	outputWeights  []int16 // the side to move's half first
+	outputBias     int32
+}
+
+const (
+	featureCount = 64 * 10 * 64
+
+	activationMax = 255 // the clipped ReLU's ceiling, also the first layer's scale
+	outputScale   = 64  // how much the output weights are scaled
+	evalScale     = 400 // output units to centipawns
+)
+
+// magic starts every network file, and changes if the format does
+const magic = "CACTINN1"
+
+// NewNetwork creates a network with every weight 0
+func NewNetwork(hidden int) *Network {
+	return &Network{
+		hidden:         hidden,
+		featureWeights: make([]int16, featureCount*hidden),
+		featureBiases:  make([]int16, hidden),
+		outputWeights:  make([]int16, 2*hidden),
+	}
+}
+
+/*
+ReadNetwork reads a network, which is stored little endian as
+
+	magic             8 bytes, "CACTINN1"
+	hidden            uint32
+	feature weights   int16 × 40960 × hidden
+	feature biases    int16 × hidden
+	output weights    int16 × 2 × hidden
+	output bias       int32
+*/
+func ReadNetwork(r io.Reader) (*Network, error) {
+	header := make([]byte, len(magic))
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("reading header: %v", err)
+	}
+	if string(header) != magic {
+		return nil, fmt.Errorf("not a network file, found %q", header)
+	}
+
+	var hidden uint32
+	if err := binary.Read(r, binary.LittleEndian, &hidden); err != nil {
+		return nil, fmt.Errorf("reading size: %v", err)
+	}
+	if hidden == 0 || hidden > 4096 {
+		return nil, fmt.Errorf("unexpected hidden size %d", hidden)
+	}
+
+	n := NewNetwork(int(hidden))
+	for _, data := range []interface{}{n.featureWeights, n.featureBiases, n.outputWeights, &n.outputBias} {
+		if err := binary.Read(r, binary.LittleEndian, data); err != nil {
+			return nil, fmt.Errorf("reading weights: %v", err)
+		}
+	}
+	return n, nil
+}
+
+// LoadNetwork reads a network from a file, see ReadNetwork
+func LoadNetwork(path string) (*Network, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadNetwork(bufio.NewReader(file))
+}
+
+// Write writes the network, as read by ReadNetwork
+func (n *Network) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, magic); err != nil {
+		return err
+	}
+	for _, data := range []interface{}{uint32(n.hidden), n.featureWeights, n.featureBiases, n.outputWeights, n.outputBias} {
+		if err := binary.Write(w, binary.LittleEndian, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// featureIndex is the feature for a piece on a 64 based square, as seen
+// by a side with its king on kingSq. Black sees the board upside down, so
+// both sides see their own pieces the same way.
+func featureIndex(perspective, kingSq int, pce position.Piece, sq int) int {
+	piece, color := int(pce-position.PwP), position.WHITE
+	if pce >= position.PbP {
+		piece, color = int(pce-position.PbP), position.BLACK
+	}
+
+	if perspective == position.BLACK {
+		kingSq ^= 56
+		sq ^= 56
+	}
+
+	theirs := 0
+	if color != perspective {
+		theirs = 1
+	}
+	return (kingSq*10+piece*2+theirs)*64 + sq
+}
+
+// output runs the rest of the network from the first layer of each side,
+// returning centipawns for the side to move
+func (n *Network) output(us, them []int32) int {
+	sum := int64(n.outputBias)
+	for i := 0; i < n.hidden; i++ {
+		sum += int64(clippedReLU(us[i])) * int64(n.outputWeights[i])
+		sum += int64(clippedReLU(them[i])) * int64(n.outputWeights[n.hidden+i])
+	}
+	return int(sum * evalScale / (activationMax * outputScale))
+}
+
+func clippedReLU(x int32) int32 {
+	if x < 0 {
+		return 0
+	}
+	if x > activationMax {
+		return activationMax
+	}
+	return x
+}
@@ -0,0 +1,98 @@
+package nnue
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// randomNetwork creates a network with small random weights,
+// so every feature changes the score a little
+func randomNetwork(seed int64, hidden int) *Network {
+	rng := rand.New(rand.NewSource(seed))
+	n := NewNetwork(hidden)
+	for i := range n.featureWeights {
+		n.featureWeights[i] = int16(rng.Intn(41) - 20)
+	}
+	for i := range n.featureBiases {
+		n.featureBiases[i] = int16(rng.Intn(201) - 50)
+	}
+	for i := range n.outputWeights {
+		n.outputWeights[i] = int16(rng.Intn(129) - 64)
+	}
+	n.outputBias = int32(rng.Intn(2001) - 1000)
+	return n
+}
+
+func TestNetwork_Write(t *testing.T) {
+	net := randomNetwork(1, 8)
+
+	buf := &bytes.Buffer{}
+	require.Nil(t, net.Write(buf))
+	assert.Equal(t, len(magic)+4+2*(featureCount*8+8+16)+4, buf.Len())
+
+	read, err := ReadNetwork(buf)
+	require.Nil(t, err)
+	assert.Equal(t, net, read)
+}
+
+func TestReadNetwork(t *testing.T) {
+	valid := &bytes.Buffer{}
+	require.Nil(t, randomNetwork(1, 4).Write(valid))
+
+	type testCase struct {
+		name string
+		data []byte
+		err  string
+	}
+	testCases := []testCase{
+		{
+			name: "empty",
+			data: []byte{},
+			err:  "reading header: EOF",
+		},
+		{
+			name: "wrong magic",
+			data: append([]byte("CACTINN0"), valid.Bytes()[len(magic):]...),
+			err:  `not a network file, found "CACTINN0"`,
+		},
+		{
+			name: "no hidden neurons",
+			data: append([]byte(magic), 0, 0, 0, 0),
+			err:  "unexpected hidden size 0",
+		},
+		{
+			name: "truncated",
+			data: valid.Bytes()[:valid.Len()-2],
+			err:  "reading weights: unexpected EOF",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ReadNetwork(bytes.NewReader(tc.data))
+			require.NotNil(t, err)
+			assert.Equal(t, tc.err, err.Error())
+		})
+	}
+}
+
+func TestLoadNetwork(t *testing.T) {
+	net := randomNetwork(2, 4)
+	path := filepath.Join(t.TempDir(), "net.nnue")
+
+	file, err := os.Create(path)
+	require.Nil(t, err)
+	require.Nil(t, net.Write(file))
+	require.Nil(t, file.Close())
+
+	loaded, err := LoadNetwork(path)
+	require.Nil(t, err)
+	assert.Equal(t, net, loaded)
+
+	_, err = LoadNetwork(filepath.Join(t.TempDir(), "missing.nnue"))
+	assert.NotNil(t, err)
+}
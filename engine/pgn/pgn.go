@@ -0,0 +1,126 @@
+package pgn
+
+import (
+	"cacti-chess/engine/position"
+	"fmt"
+)
+
+// Portable Game Notation (PGN) is the standard text format for chess games.
+// https://www.thechessdrum.net/PGN_Reference.txt
+
+const StartingFen = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+// Game results, used for both the movetext termination and the Result tag
+const (
+	ResultWhiteWins = "1-0"
+	ResultBlackWins = "0-1"
+	ResultDraw      = "1/2-1/2"
+	ResultUnknown   = "*"
+)
+
+// sevenTagRoster are the tags every PGN game should have, in export order
+var sevenTagRoster = []string{"Event", "Site", "Date", "Round", "White", "Black", "Result"}
+
+// Tag is a single [Name "Value"] pair
+type Tag struct {
+	Name  string
+	Value string
+}
+
+// Move is a single move of the game, along with any annotations
+type Move struct {
+	SAN           string
+	Key           position.Movekey
+	NAGs          []int     // numeric annotation glyphs, i.e. $1 for !
+	CommentBefore string    // comment given before the move
+	CommentAfter  string    // comment given after the move
+	Variations    [][]*Move // alternatives to this move
+}
+
+// Game is a parsed PGN game. Moves holds the main line, with every
+// move already resolved to a Movekey against the position it was played in.
+type Game struct {
+	Tags    []Tag
+	Moves   []*Move
+	Result  string
+	Comment string // comment with no moves to attach to
+}
+
+// New creates an empty game with the seven tag roster set to unknown.
+// If fen isn't the standard starting position, the SetUp and FEN tags are added.
+func New(fen string) *Game {
+	g := &Game{Result: ResultUnknown}
+	for _, name := range sevenTagRoster {
+		g.SetTag(name, "?")
+	}
+	g.SetTag("Result", ResultUnknown)
+
+	if fen != "" && fen != StartingFen {
+		g.SetTag("SetUp", "1")
+		g.SetTag("FEN", fen)
+	}
+	return g
+}
+
+// GetTag returns the value of a tag, or "" if it isn't set
+func (g *Game) GetTag(name string) string {
+	for _, t := range g.Tags {
+		if t.Name == name {
+			return t.Value
+		}
+	}
+	return ""
+}
+
+// SetTag updates an existing tag, or adds it to the end of the list
+func (g *Game) SetTag(name, value string) {
+	for i, t := range g.Tags {
+		if t.Name == name {
+			g.Tags[i].Value = value
+			return
+		}
+	}
+	g.Tags = append(g.Tags, Tag{name, value})
+}
+
+// SetResult sets both the game termination and the Result tag
+func (g *Game) SetResult(result string) {
+	g.Result = result
+	g.SetTag("Result", result)
+}
+
+// StartPosition returns the position the game started from, which
+// is the FEN tag if there is one, otherwise the standard starting position
+func (g *Game) StartPosition() (*position.Position, error) {
+	fen := g.GetTag("FEN")
+	if fen == "" {
+		fen = StartingFen
+	}
+	return position.FromFen(fen)
+}
+
+// AddMove appends mv to the main line and makes it on p, which should
+// be the current position of the game.
+func (g *Game) AddMove(p *position.Position, mv position.Movekey) error {
+	san := p.ToSAN(mv)
+	if !p.MakeMove(mv) {
+		return fmt.Errorf("move %v is not legal", mv.ShortString())
+	}
+	g.Moves = append(g.Moves, &Move{SAN: san, Key: mv})
+	return nil
+}
+
+// Replay returns the position reached after every move of the main line
+func (g *Game) Replay() (*position.Position, error) {
+	p, err := g.StartPosition()
+	if err != nil {
+		return nil, fmt.Errorf("error parsing fen: %v", err)
+	}
+
+	for i, mv := range g.Moves {
+		if !p.MakeMove(mv.Key) {
+			return nil, fmt.Errorf("move %d (%v) is not legal", i+1, mv.SAN)
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,366 @@
+package pgn
+
+import (
+	"cacti-chess/engine/position"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenType int
+
+const (
+	tokenTag tokenType = iota
+	tokenComment
+	tokenNAG
+	tokenVariationStart
+	tokenVariationEnd
+	tokenResult
+	tokenSymbol
+)
+
+type token struct {
+	typ   tokenType
+	name  string // tag name
+	value string // tag value, comment text, symbol, etc
+	line  int
+}
+
+// suffixNAGs maps move suffix annotations to their NAG equivalents
+var suffixNAGs = map[string]int{
+	"!":  1,
+	"?":  2,
+	"!!": 3,
+	"??": 4,
+	"!?": 5,
+	"?!": 6,
+}
+
+// tokenize splits PGN text into tokens. Move numbers and escape
+// lines are dropped since nothing needs them.
+func tokenize(text string) ([]token, error) {
+	var tokens []token
+	runes := []rune(text)
+	line := 1
+
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+
+		switch {
+		case c == '\n':
+			line++
+			// lines starting with % are escaped
+			if i+1 < len(runes) && runes[i+1] == '%' {
+				for i+1 < len(runes) && runes[i+1] != '\n' {
+					i++
+				}
+			}
+		case unicode.IsSpace(c):
+		case c == '%' && i == 0:
+			for i+1 < len(runes) && runes[i+1] != '\n' {
+				i++
+			}
+		case c == '[':
+			end := i + 1
+			for end < len(runes) && runes[end] != ']' {
+				// skip over escaped characters in the value
+				if runes[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("line %d: unterminated tag", line)
+			}
+			name, value, err := parseTag(string(runes[i+1 : end]))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			tokens = append(tokens, token{typ: tokenTag, name: name, value: value, line: line})
+			i = end
+		case c == '{':
+			end := i + 1
+			for end < len(runes) && runes[end] != '}' {
+				end++
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			comment := string(runes[i+1 : end])
+			tokens = append(tokens, token{typ: tokenComment, value: strings.Join(strings.Fields(comment), " "), line: line})
+			line += strings.Count(comment, "\n")
+			i = end
+		case c == ';':
+			end := i + 1
+			for end < len(runes) && runes[end] != '\n' {
+				end++
+			}
+			tokens = append(tokens, token{typ: tokenComment, value: strings.TrimSpace(string(runes[i+1 : end])), line: line})
+			i = end - 1
+		case c == '(':
+			tokens = append(tokens, token{typ: tokenVariationStart, line: line})
+		case c == ')':
+			tokens = append(tokens, token{typ: tokenVariationEnd, line: line})
+		case c == '$':
+			end := i + 1
+			for end < len(runes) && unicode.IsDigit(runes[end]) {
+				end++
+			}
+			nag, err := strconv.Atoi(string(runes[i+1 : end]))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid NAG", line)
+			}
+			tokens = append(tokens, token{typ: tokenNAG, value: strconv.Itoa(nag), line: line})
+			i = end - 1
+		case c == '*':
+			tokens = append(tokens, token{typ: tokenResult, value: ResultUnknown, line: line})
+		default:
+			end := i
+			for end < len(runes) && isSymbolRune(runes[end]) {
+				end++
+			}
+			if end == i {
+				return nil, fmt.Errorf("line %d: unexpected character %q", line, c)
+			}
+
+			// move numbers are digits followed by periods, i.e. 1. or 12...
+			if unicode.IsDigit(c) {
+				digits := i
+				for digits < end && unicode.IsDigit(runes[digits]) {
+					digits++
+				}
+				if digits < end && runes[digits] == '.' {
+					for digits < end && runes[digits] == '.' {
+						digits++
+					}
+					i = digits - 1
+					continue
+				}
+			}
+
+			sym := string(runes[i:end])
+			switch sym {
+			case ResultWhiteWins, ResultBlackWins, ResultDraw:
+				tokens = append(tokens, token{typ: tokenResult, value: sym, line: line})
+			default:
+				tokens = append(tokens, token{typ: tokenSymbol, value: sym, line: line})
+			}
+			i = end - 1
+		}
+	}
+
+	return tokens, nil
+}
+
+func isSymbolRune(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || strings.ContainsRune("_+#=:-/.!?", c)
+}
+
+// parseTag parses the inside of a tag pair, i.e. Event "Casual Game"
+func parseTag(str string) (string, string, error) {
+	str = strings.TrimSpace(str)
+	split := strings.IndexFunc(str, unicode.IsSpace)
+	if split < 0 {
+		return "", "", fmt.Errorf("tag %q has no value", str)
+	}
+
+	name := str[:split]
+	value := strings.TrimSpace(str[split:])
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return "", "", fmt.Errorf("tag %q value should be quoted", name)
+	}
+	value = value[1 : len(value)-1]
+	value = strings.ReplaceAll(value, `\"`, `"`)
+	value = strings.ReplaceAll(value, `\\`, `\`)
+
+	return name, value, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (ps *parser) done() bool {
+	return ps.pos >= len(ps.tokens)
+}
+
+func (ps *parser) peek() token {
+	return ps.tokens[ps.pos]
+}
+
+// parseGame reads the tag pairs + movetext of the next game
+func (ps *parser) parseGame() (*Game, error) {
+	g := &Game{Result: ResultUnknown}
+
+	for !ps.done() && ps.peek().typ == tokenTag {
+		tk := ps.peek()
+		g.SetTag(tk.name, tk.value)
+		ps.pos++
+	}
+
+	p, err := g.StartPosition()
+	if err != nil {
+		return nil, fmt.Errorf("error parsing fen: %v", err)
+	}
+
+	moves, comment, err := ps.parseLine(g, p, 0)
+	if err != nil {
+		return nil, err
+	}
+	g.Moves = moves
+	g.Comment = comment
+
+	return g, nil
+}
+
+// parseLine reads moves into a line, making them on p as it goes. Variations
+// are parsed recursively, with depth 0 being the main line. Any moves made
+// in a variation are undone before returning, so p is left as it was.
+func (ps *parser) parseLine(g *Game, p *position.Position, depth int) ([]*Move, string, error) {
+	var moves []*Move
+	pendingComment := ""
+	made := 0
+
+	defer func() {
+		if depth > 0 {
+			for i := 0; i < made; i++ {
+				p.UndoMove()
+			}
+		}
+	}()
+
+	for !ps.done() {
+		tk := ps.peek()
+
+		switch tk.typ {
+		case tokenTag:
+			// a new game started without a result
+			if depth > 0 {
+				return nil, "", fmt.Errorf("line %d: unterminated variation", tk.line)
+			}
+			return moves, pendingComment, nil
+		case tokenResult:
+			if depth > 0 {
+				return nil, "", fmt.Errorf("line %d: result inside a variation", tk.line)
+			}
+			ps.pos++
+			g.Result = tk.value
+			return moves, pendingComment, nil
+		case tokenVariationEnd:
+			if depth == 0 {
+				return nil, "", fmt.Errorf("line %d: unexpected )", tk.line)
+			}
+			ps.pos++
+			return moves, pendingComment, nil
+		case tokenComment:
+			ps.pos++
+			if len(moves) == 0 {
+				pendingComment = joinComment(pendingComment, tk.value)
+			} else {
+				last := moves[len(moves)-1]
+				last.CommentAfter = joinComment(last.CommentAfter, tk.value)
+			}
+		case tokenNAG:
+			ps.pos++
+			if len(moves) == 0 {
+				return nil, "", fmt.Errorf("line %d: NAG before any move", tk.line)
+			}
+			nag, _ := strconv.Atoi(tk.value)
+			last := moves[len(moves)-1]
+			last.NAGs = append(last.NAGs, nag)
+		case tokenVariationStart:
+			ps.pos++
+			if len(moves) == 0 {
+				return nil, "", fmt.Errorf("line %d: variation before any move", tk.line)
+			}
+			// the variation replaces the last move, so take it back first
+			last := moves[len(moves)-1]
+			p.UndoMove()
+			variation, comment, err := ps.parseLine(g, p, depth+1)
+			if err != nil {
+				return nil, "", err
+			}
+			p.MakeMove(last.Key)
+			if len(variation) > 0 {
+				variation[0].CommentBefore = joinComment(comment, variation[0].CommentBefore)
+				last.Variations = append(last.Variations, variation)
+			}
+		case tokenSymbol:
+			ps.pos++
+			if tk.value == "e.p." {
+				continue
+			}
+
+			san, nag := splitSuffix(tk.value)
+			mv, err := p.ParseSAN(san)
+			if err != nil {
+				return nil, "", fmt.Errorf("line %d: %v", tk.line, err)
+			}
+			move := &Move{SAN: p.ToSAN(mv), Key: mv, CommentBefore: pendingComment}
+			if nag != 0 {
+				move.NAGs = append(move.NAGs, nag)
+			}
+			pendingComment = ""
+
+			p.MakeMove(mv)
+			made++
+			moves = append(moves, move)
+		}
+	}
+
+	if depth > 0 {
+		return nil, "", fmt.Errorf("unterminated variation")
+	}
+	return moves, pendingComment, nil
+}
+
+// splitSuffix separates a move from any !/? annotation, returning the NAG for it
+func splitSuffix(sym string) (string, int) {
+	san := strings.TrimRight(sym, "!?")
+	return san, suffixNAGs[sym[len(san):]]
+}
+
+func joinComment(a, b string) string {
+	if a == "" {
+		return b
+	}
+	if b == "" {
+		return a
+	}
+	return a + " " + b
+}
+
+// Parse reads every game from a PGN file. Each move is checked
+// for legality and resolved to a Movekey.
+func Parse(r io.Reader) ([]*Game, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading pgn: %v", err)
+	}
+
+	tokens, err := tokenize(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	ps := &parser{tokens: tokens}
+	var games []*Game
+	for !ps.done() {
+		g, err := ps.parseGame()
+		if err != nil {
+			return nil, fmt.Errorf("game %d: %v", len(games)+1, err)
+		}
+		games = append(games, g)
+	}
+
+	return games, nil
+}
+
+// ParseString is a shorthand for Parse when the PGN is already in memory
+func ParseString(s string) ([]*Game, error) {
+	return Parse(strings.NewReader(s))
+}
@@ -0,0 +1,111 @@
+package pgn
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+const operaGame = `[Event "Paris"]
+[Site "Paris FRA"]
+[Date "1858.??.??"]
+[Round "?"]
+[White "Paul Morphy"]
+[Black "Duke Karl / Count Isouard"]
+[Result "1-0"]
+
+1. e4 e5 2. Nf3 d6 3. d4 Bg4 {This is a weak move already.} 4. dxe5 Bxf3 5. Qxf3
+dxe5 6. Bc4 Nf6 7. Qb3 Qe7 8. Nc3 c6 9. Bg5 {Black is in what's like a zugzwang
+position here.} b5 $6 10. Nxb5! cxb5 11. Bxb5+ Nbd7 12. O-O-O Rd8 13. Rxd7 Rxd7
+14. Rd1 Qe6 15. Bxd7+ Nxd7 16. Qb8+ Nxb8 17. Rd8# 1-0
+`
+
+func TestParse(t *testing.T) {
+	t.Run("it parses tags and movetext", func(t *testing.T) {
+		games, err := ParseString(operaGame)
+		require.Nil(t, err)
+		require.Len(t, games, 1)
+
+		g := games[0]
+		assert.Equal(t, "Paul Morphy", g.GetTag("White"))
+		assert.Equal(t, "Duke Karl / Count Isouard", g.GetTag("Black"))
+		assert.Equal(t, ResultWhiteWins, g.Result)
+		require.Len(t, g.Moves, 33)
+
+		assert.Equal(t, "e4", g.Moves[0].SAN)
+		assert.Equal(t, "This is a weak move already.", g.Moves[5].CommentAfter)
+		assert.Equal(t, []int{6}, g.Moves[17].NAGs)
+		assert.Equal(t, []int{1}, g.Moves[18].NAGs)
+		assert.Equal(t, "Nxb5", g.Moves[18].SAN)
+		assert.Equal(t, "O-O-O", g.Moves[22].SAN)
+		assert.Equal(t, "Rd8#", g.Moves[32].SAN)
+
+		p, err := g.Replay()
+		require.Nil(t, err)
+		assert.True(t, p.IsCheckmate())
+	})
+
+	t.Run("it parses nested variations", func(t *testing.T) {
+		games, err := ParseString(`1. e4 (1. d4 d5 (1... Nf6 2. c4) 2. c4) 1... e5 2. Nf3 *`)
+		require.Nil(t, err)
+		require.Len(t, games, 1)
+
+		g := games[0]
+		assert.Equal(t, ResultUnknown, g.Result)
+		require.Len(t, g.Moves, 3)
+		require.Len(t, g.Moves[0].Variations, 1)
+
+		variation := g.Moves[0].Variations[0]
+		require.Len(t, variation, 3)
+		assert.Equal(t, "d4", variation[0].SAN)
+		require.Len(t, variation[1].Variations, 1)
+		assert.Equal(t, "Nf6", variation[1].Variations[0][0].SAN)
+		assert.Equal(t, "c4", variation[1].Variations[0][1].SAN)
+	})
+
+	t.Run("it parses multiple games and comments", func(t *testing.T) {
+		text := `[Event "one"]
+
+{opening comment} 1. e4 ; rest of line comment
+e5 1/2-1/2
+
+[Event "two"]
+[SetUp "1"]
+[FEN "4k3/8/8/8/8/8/4P3/4K3 b - - 0 30"]
+
+30... Kd7 31. e4 0-1
+`
+		games, err := ParseString(text)
+		require.Nil(t, err)
+		require.Len(t, games, 2)
+
+		assert.Equal(t, ResultDraw, games[0].Result)
+		assert.Equal(t, "opening comment", games[0].Moves[0].CommentBefore)
+		assert.Equal(t, "rest of line comment", games[0].Moves[0].CommentAfter)
+
+		assert.Equal(t, "two", games[1].GetTag("Event"))
+		assert.Equal(t, ResultBlackWins, games[1].Result)
+		require.Len(t, games[1].Moves, 2)
+		assert.Equal(t, "Kd7", games[1].Moves[0].SAN)
+	})
+
+	t.Run("it accepts en passant markers", func(t *testing.T) {
+		games, err := ParseString(`1. e4 Nf6 2. e5 d5 3. exd6 e.p. *`)
+		require.Nil(t, err)
+		require.Len(t, games[0].Moves, 5)
+		assert.Equal(t, "exd6", games[0].Moves[4].SAN)
+	})
+
+	t.Run("it returns errors for bad games", func(t *testing.T) {
+		for _, text := range []string{
+			`1. e5 *`,
+			`1. e4 (1. d4 *`,
+			`1. e4 e5) *`,
+			`[Event "unterminated *`,
+			`{unterminated comment`,
+		} {
+			_, err := ParseString(text)
+			assert.NotNil(t, err, text)
+		}
+	})
+}
@@ -0,0 +1,145 @@
+package pgn
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxLineLength is the export format limit for movetext lines
+const maxLineLength = 80
+
+// lineWriter wraps movetext tokens so no line goes past maxLineLength
+type lineWriter struct {
+	b        strings.Builder
+	lineLen  int
+	joinNext bool // skip the space before the next token, i.e. after (
+}
+
+func (lw *lineWriter) write(tk string) {
+	if lw.lineLen > 0 && lw.lineLen+1+len(tk) > maxLineLength {
+		lw.b.WriteString("\n")
+		lw.lineLen = 0
+	}
+	if lw.lineLen > 0 && !lw.joinNext {
+		lw.b.WriteString(" ")
+		lw.lineLen++
+	}
+	lw.b.WriteString(tk)
+	lw.lineLen += len(tk)
+	lw.joinNext = false
+}
+
+// writeComment splits a comment into words, so long comments still wrap
+func (lw *lineWriter) writeComment(comment string) {
+	words := strings.Fields(strings.ReplaceAll(comment, "}", ""))
+	if len(words) == 0 {
+		return
+	}
+	words[0] = "{" + words[0]
+	words[len(words)-1] += "}"
+	for _, w := range words {
+		lw.write(w)
+	}
+}
+
+// writeLine writes a line of moves starting at the given half move. The move
+// number is repeated for black after anything that interrupts the line.
+func (lw *lineWriter) writeLine(moves []*Move, ply int) {
+	needNumber := true
+	for _, mv := range moves {
+		if mv.CommentBefore != "" {
+			lw.writeComment(mv.CommentBefore)
+			needNumber = true
+		}
+
+		if ply%2 == 0 {
+			lw.write(fmt.Sprintf("%d.", ply/2+1))
+		} else if needNumber {
+			lw.write(fmt.Sprintf("%d...", ply/2+1))
+		}
+		lw.write(mv.SAN)
+		needNumber = false
+
+		for _, nag := range mv.NAGs {
+			lw.write(fmt.Sprintf("$%d", nag))
+		}
+		if mv.CommentAfter != "" {
+			lw.writeComment(mv.CommentAfter)
+			needNumber = true
+		}
+		for _, variation := range mv.Variations {
+			lw.write("(")
+			lw.joinNext = true
+			lw.writeLine(variation, ply)
+			lw.b.WriteString(")")
+			lw.lineLen++
+			needNumber = true
+		}
+
+		ply++
+	}
+}
+
+// String returns the game in PGN export format
+func (g *Game) String() string {
+	b := strings.Builder{}
+
+	// seven tag roster first, then everything else in order
+	for _, name := range sevenTagRoster {
+		value := g.GetTag(name)
+		if name == "Result" {
+			value = g.Result
+		} else if value == "" {
+			value = "?"
+		}
+		writeTag(&b, name, value)
+	}
+	for _, t := range g.Tags {
+		if !isSevenTagRoster(t.Name) {
+			writeTag(&b, t.Name, t.Value)
+		}
+	}
+	b.WriteString("\n")
+
+	// the first half move comes from the starting position, since
+	// a game set up from a fen could start on black's turn
+	ply := 0
+	if p, err := g.StartPosition(); err == nil {
+		ply = p.GetHisPly()
+	}
+
+	lw := &lineWriter{}
+	if g.Comment != "" {
+		lw.writeComment(g.Comment)
+	}
+	lw.writeLine(g.Moves, ply)
+	lw.write(g.Result)
+
+	b.WriteString(lw.b.String())
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+// Write writes the game in PGN export format, followed by a blank
+// line so multiple games can be written to the same file
+func Write(w io.Writer, g *Game) error {
+	_, err := io.WriteString(w, g.String()+"\n")
+	return err
+}
+
+func writeTag(b *strings.Builder, name, value string) {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	b.WriteString(fmt.Sprintf("[%s \"%s\"]\n", name, value))
+}
+
+func isSevenTagRoster(name string) bool {
+	for _, n := range sevenTagRoster {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
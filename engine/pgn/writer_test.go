@@ -0,0 +1,88 @@
+package pgn
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestGame_String(t *testing.T) {
+	t.Run("it writes a played game", func(t *testing.T) {
+		g := New("")
+		g.SetTag("White", "cacti-chess")
+		g.SetTag("Annotator", `"quoted"`)
+
+		p, err := g.StartPosition()
+		require.Nil(t, err)
+
+		for _, mv := range []string{"e2e4", "e7e5", "g1f3", "b8c6"} {
+			key, err := p.ParseMove(mv)
+			require.Nil(t, err)
+			require.Nil(t, g.AddMove(p, key))
+		}
+		g.SetResult(ResultDraw)
+
+		want := `[Event "?"]
+[Site "?"]
+[Date "?"]
+[Round "?"]
+[White "cacti-chess"]
+[Black "?"]
+[Result "1/2-1/2"]
+[Annotator "\"quoted\""]
+
+1. e4 e5 2. Nf3 Nc6 1/2-1/2
+`
+		assert.Equal(t, want, g.String())
+	})
+
+	t.Run("it writes annotations and variations", func(t *testing.T) {
+		text := `[Event "?"]
+[Site "?"]
+[Date "?"]
+[Round "?"]
+[White "?"]
+[Black "?"]
+[Result "*"]
+
+{start} 1. e4 $1 {best} 1... e5 (1... c5 2. Nf3 (2. c3) 2... d6) 2. Nf3 *
+`
+		games, err := ParseString(text)
+		require.Nil(t, err)
+		assert.Equal(t, text, games[0].String())
+	})
+
+	t.Run("it starts from the fen move number", func(t *testing.T) {
+		g := New("4k3/8/8/8/8/8/4P3/4K3 b - - 0 30")
+		p, err := g.StartPosition()
+		require.Nil(t, err)
+
+		key, err := p.ParseSAN("Kd7")
+		require.Nil(t, err)
+		require.Nil(t, g.AddMove(p, key))
+		key, err = p.ParseSAN("e4")
+		require.Nil(t, err)
+		require.Nil(t, g.AddMove(p, key))
+
+		assert.True(t, strings.HasSuffix(g.String(), "\n30... Kd7 31. e4 *\n"))
+		assert.Equal(t, "1", g.GetTag("SetUp"))
+	})
+
+	t.Run("it wraps long lines and round trips", func(t *testing.T) {
+		games, err := ParseString(operaGame)
+		require.Nil(t, err)
+
+		buf := &bytes.Buffer{}
+		require.Nil(t, Write(buf, games[0]))
+		for _, line := range strings.Split(buf.String(), "\n") {
+			assert.LessOrEqual(t, len(line), maxLineLength)
+		}
+
+		reparsed, err := Parse(buf)
+		require.Nil(t, err)
+		require.Len(t, reparsed, 1)
+		assert.Equal(t, games[0], reparsed[0])
+	})
+}
@@ -0,0 +1,42 @@
+package position
+
+// GetAttacks returns the squares attacked by the piece on a 120 based
+// square, as a bitboard like GetPawns. Like IsSquareAttacked, sliding
+// pieces stop at the first piece in each direction, which is included
+// whichever side it belongs to. An empty square attacks nothing.
+func (p *Position) GetAttacks(sq int) uint64 {
+	pce := p.pieces[sq]
+	attacks := uint64(0)
+
+	switch pce {
+	case EMPTY:
+		return 0
+	case PwP:
+		for _, tsq := range [2]int{sq + 9, sq + 11} {
+			if !sqOffBoard(tsq) {
+				attacks |= 1 << SQ64(tsq)
+			}
+		}
+		return attacks
+	case PbP:
+		for _, tsq := range [2]int{sq - 9, sq - 11} {
+			if !sqOffBoard(tsq) {
+				attacks |= 1 << SQ64(tsq)
+			}
+		}
+		return attacks
+	}
+
+	meta := pieceLookups[pce]
+	for _, dir := range meta.dir {
+		tsq := sq + dir
+		for !sqOffBoard(tsq) {
+			attacks |= 1 << SQ64(tsq)
+			if !meta.slides || p.pieces[tsq] != EMPTY {
+				break
+			}
+			tsq += dir
+		}
+	}
+	return attacks
+}
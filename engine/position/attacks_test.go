@@ -0,0 +1,54 @@
+package position
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/bits"
+	"testing"
+)
+
+func TestPosition_GetAttacks(t *testing.T) {
+	type testCase struct {
+		name string
+		fen  string
+		sq   int
+		want int // number of squares attacked
+	}
+
+	for _, tc := range []testCase{
+		{"empty square", "4k3/8/8/8/8/8/8/4K3 w - - 0 1", E4, 0},
+		{"knight in the corner", "4k3/8/8/8/8/8/8/N3K3 w - - 0 1", A1, 2},
+		{"knight in the centre", "4k3/8/8/8/3N4/8/8/4K3 w - - 0 1", D4, 8},
+		{"white pawn on the edge", "4k3/8/8/8/8/8/P7/4K3 w - - 0 1", A2, 1},
+		{"black pawn", "4k3/8/4p3/8/8/8/8/4K3 w - - 0 1", E6, 2},
+		{"king", "4k3/8/8/8/8/8/8/4K3 w - - 0 1", E1, 5},
+		{"rook on an open board", "4k3/8/8/8/8/8/8/R5K1 w - - 0 1", A1, 13},
+		{"rook stopped by pieces", "4k3/8/8/8/p7/8/8/R1N3K1 w - - 0 1", A1, 5},
+		{"queen in the centre", "4k3/8/8/8/3Q4/8/8/K7 w - - 0 1", D4, 27},
+		{"bishops at the start", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", C1, 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := FromFen(tc.fen)
+			require.Nil(t, err)
+			assert.Equal(t, tc.want, bits.OnesCount64(p.GetAttacks(tc.sq)))
+		})
+	}
+
+	t.Run("it agrees with IsSquareAttacked", func(t *testing.T) {
+		p, err := FromFen("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+		require.Nil(t, err)
+
+		for color := WHITE; color <= BLACK; color++ {
+			attacked := uint64(0)
+			for sq := 0; sq < 64; sq++ {
+				pce := p.GetPiece(SQ120(sq))
+				if pce != EMPTY && pieceLookups[pce].color == color {
+					attacked |= p.GetAttacks(SQ120(sq))
+				}
+			}
+			for sq := 0; sq < 64; sq++ {
+				assert.Equal(t, p.IsSquareAttacked(SQ120(sq), color), attacked&(1<<sq) != 0, printSq(SQ120(sq)))
+			}
+		}
+	})
+}
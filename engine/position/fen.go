@@ -59,17 +59,74 @@ func FromFen(fen string) (*Position, error) {
 		return nil, fmt.Errorf("error parsing fullmoveStr: %v", err)
 	}
 
-	state.hisPly = fullmovesCount / 2
+	// the full move count starts at 1 and increments after black moves
+	if fullmovesCount < 1 {
+		fullmovesCount = 1
+	}
+	state.hisPly = (fullmovesCount - 1) * 2
 	if state.side == BLACK {
 		state.hisPly++
 	}
 
 	// posKey
 	state.posKey = state.GenPosKey()
+	state.pawnKey = state.GenPawnKey()
 	state.updateListCaches()
 	return state, nil
 }
 
+// ToFen returns the fen string for the current state of the position.
+// It is the inverse of FromFen, so FromFen(p.ToFen()) gives back an
+// equivalent position.
+func (p *Position) ToFen() string {
+	b := strings.Builder{}
+
+	// pieces, starting from A8 and working down to H1
+	for r := RANK_8; r >= RANK_1; r-- {
+		emptySpaces := 0
+		for f := FILE_A; f <= FILE_H; f++ {
+			pce := p.pieces[fileRankToSq(f, r)]
+			if pce == EMPTY {
+				emptySpaces++
+				continue
+			}
+			if emptySpaces > 0 {
+				b.WriteString(strconv.Itoa(emptySpaces))
+				emptySpaces = 0
+			}
+			b.WriteString(pce.String())
+		}
+		if emptySpaces > 0 {
+			b.WriteString(strconv.Itoa(emptySpaces))
+		}
+		if r != RANK_1 {
+			b.WriteString("/")
+		}
+	}
+
+	// side
+	if p.side == WHITE {
+		b.WriteString(" w ")
+	} else {
+		b.WriteString(" b ")
+	}
+
+	// castlePerms
+	b.WriteString(p.castlePerm.String())
+
+	// enPas
+	if p.enPas == NO_SQ {
+		b.WriteString(" -")
+	} else {
+		b.WriteString(" " + printSq(p.enPas))
+	}
+
+	// fifty move + full move counters
+	b.WriteString(fmt.Sprintf(" %d %d", p.fiftyMove, p.hisPly/2+1))
+
+	return b.String()
+}
+
 func parseEnPas(enPasStr string) (int, error) {
 	if enPasStr == "-" {
 		return NO_SQ, nil
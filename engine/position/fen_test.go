@@ -1,8 +1,11 @@
 package position
 
 import (
+	"bufio"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"os"
+	"strings"
 	"testing"
 )
 
@@ -98,3 +101,57 @@ func Test_FromFen(t *testing.T) {
 		assert.Equal(t, want, state.pieces)
 	})
 }
+
+func Test_ToFen(t *testing.T) {
+	t.Run("it round trips fens", func(t *testing.T) {
+		fens := []string{
+			"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+			"r3k2r/8/8/8/8/8/8/R3K2R b Kq - 12 40",
+			"8/8/8/8/8/8/8/k6K w - - 0 100",
+			"1r2k2r/8/8/8/8/8/4K3/R6R w KQkq - 0 1", // castle perms without the king or rook
+		}
+
+		for _, fen := range fens {
+			p, err := FromFen(fen)
+			require.Nil(t, err)
+			assert.Equal(t, fen, p.ToFen())
+		}
+	})
+
+	t.Run("it round trips all perft test cases", func(t *testing.T) {
+		file, err := os.Open("../perft/perft_test_cases.txt")
+		require.Nil(t, err)
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			fen := strings.TrimSpace(strings.Split(scanner.Text(), ",")[0]) + " 0 1"
+			p, err := FromFen(fen)
+			require.Nil(t, err)
+			require.Equal(t, fen, p.ToFen())
+		}
+	})
+
+	t.Run("it tracks moves made", func(t *testing.T) {
+		p, err := FromFen("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+		require.Nil(t, err)
+
+		moves := []struct {
+			move string
+			fen  string
+		}{
+			{"e2e4", "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"},
+			{"g8f6", "rnbqkb1r/pppppppp/5n2/8/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 1 2"},
+			{"e1e2", "rnbqkb1r/pppppppp/5n2/8/4P3/8/PPPPKPPP/RNBQ1BNR b kq - 2 2"},
+			{"f6e4", "rnbqkb1r/pppppppp/8/8/4n3/8/PPPPKPPP/RNBQ1BNR w kq - 0 3"},
+		}
+
+		for _, mv := range moves {
+			key, err := p.ParseMove(mv.move)
+			require.Nil(t, err)
+			require.True(t, p.MakeMove(key))
+			assert.Equal(t, mv.fen, p.ToFen())
+		}
+	})
+}
@@ -0,0 +1,74 @@
+package position
+
+/*
+Flip returns the position with the colors swapped, i.e. the board turned
+upside down with every white piece made black and every black piece made
+white, and the other side to move. The flipped position is the same
+position for the other side, so anything scored from white's side
+should come out the same but negated.
+
+Like FromFen, the flipped position has no history of moves to undo.
+*/
+func (p *Position) Flip() *Position {
+	f := p.transform(func(sq int) int { return sq ^ 56 })
+
+	for sq := 0; sq < 64; sq++ {
+		if pce := f.pieces[SQ120(sq)]; pce != EMPTY {
+			f.pieces[SQ120(sq)] = flipColor(pce)
+		}
+	}
+	f.side = p.side ^ 1
+
+	// white's castle perms are the low 2 bits, and black's the high 2
+	f.castlePerm = &castlePerm{p.castlePerm.val>>2 | p.castlePerm.val&3<<2}
+
+	f.updateCaches()
+	return f
+}
+
+/*
+Mirror returns the position reflected left to right, with the same side to
+move. Castling isn't symmetric, so the mirrored position can't castle, but
+nothing else should favour a side of the board. As with Flip, there's no
+history to undo.
+*/
+func (p *Position) Mirror() *Position {
+	m := p.transform(func(sq int) int { return sq ^ 7 })
+	m.side = p.side
+	m.castlePerm = &castlePerm{CASTLE_PERMS_NONE}
+	m.updateCaches()
+	return m
+}
+
+// transform moves every piece and the en passant square
+// to a new 64 based square, keeping the move counters
+func (p *Position) transform(to func(sq int) int) *Position {
+	t := &Position{}
+	t.Reset()
+
+	for sq := 0; sq < 64; sq++ {
+		t.pieces[SQ120(to(sq))] = p.pieces[SQ120(sq)]
+	}
+	if p.enPas != NO_SQ {
+		t.enPas = SQ120(to(SQ64(p.enPas)))
+	}
+	t.fiftyMove = p.fiftyMove
+	t.hisPly = p.hisPly
+	return t
+}
+
+// updateCaches works out everything kept alongside the pieces,
+// once they're all on the board
+func (p *Position) updateCaches() {
+	p.posKey = p.GenPosKey()
+	p.pawnKey = p.GenPawnKey()
+	p.updateListCaches()
+}
+
+// flipColor is the same piece for the other side
+func flipColor(pce Piece) Piece {
+	if pce >= PbP {
+		return pce - 6
+	}
+	return pce + 6
+}
@@ -0,0 +1,101 @@
+package position
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestPosition_Flip(t *testing.T) {
+	type testCase struct {
+		name string
+		fen  string
+		want string
+	}
+
+	for _, tc := range []testCase{
+		{
+			"starting position",
+			"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR b KQkq - 0 1",
+		},
+		{
+			"one side's castle perms and en passant",
+			"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQK2R w Kkq f6 0 3",
+			"rnbqk2r/pppp1ppp/8/8/3PpP2/8/PPP1P1PP/RNBQKBNR b KQk f3 0 3",
+		},
+		{
+			"move counters",
+			"4k3/8/8/8/8/8/4P3/4K3 b - - 12 40",
+			"4k3/4p3/8/8/8/8/8/4K3 w - - 12 40",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := FromFen(tc.fen)
+			require.Nil(t, err)
+
+			flipped := p.Flip()
+			require.Nil(t, flipped.AssertCache())
+
+			want, err := FromFen(tc.want)
+			require.Nil(t, err)
+			assert.Equal(t, want.ToFen(), flipped.ToFen())
+			assert.Equal(t, want.GetPosKey(), flipped.GetPosKey())
+			assert.Equal(t, want.GetPawnKey(), flipped.GetPawnKey())
+
+			// flipping twice gives back the same position
+			assert.Equal(t, p.ToFen(), flipped.Flip().ToFen())
+		})
+	}
+
+	t.Run("it leaves the original alone", func(t *testing.T) {
+		fen := "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"
+		p, err := FromFen(fen)
+		require.Nil(t, err)
+
+		p.Flip()
+		assert.Equal(t, fen, p.ToFen())
+	})
+
+	t.Run("it has the same moves", func(t *testing.T) {
+		p, err := FromFen("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+		require.Nil(t, err)
+		assert.Len(t, *p.Flip().GenerateLegalMoves(), len(*p.GenerateLegalMoves()))
+	})
+}
+
+func TestPosition_Mirror(t *testing.T) {
+	type testCase struct {
+		name string
+		fen  string
+		want string
+	}
+
+	for _, tc := range []testCase{
+		{
+			"pieces and en passant",
+			"4k3/8/8/3pP3/8/8/1N6/R3K3 w - d6 0 1",
+			"3k4/8/8/3Pp3/8/8/6N1/3K3R w - e6 0 1",
+		},
+		{
+			"castle perms are lost",
+			"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR b KQkq - 0 1",
+			"rnbkqbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBKQBNR b - - 0 1",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := FromFen(tc.fen)
+			require.Nil(t, err)
+
+			mirrored := p.Mirror()
+			require.Nil(t, mirrored.AssertCache())
+			assert.Equal(t, tc.want, mirrored.ToFen())
+		})
+	}
+
+	t.Run("it has the same moves without castling", func(t *testing.T) {
+		p, err := FromFen("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w - - 0 1")
+		require.Nil(t, err)
+		assert.Len(t, *p.Mirror().GenerateLegalMoves(), len(*p.GenerateLegalMoves()))
+	})
+}
@@ -24,8 +24,9 @@ Making a move...
 
 // hash keys?
 
-// clear a single sq from the position
-func (p *Position) clearPiece(sq int) {
+// clear a single sq from the position, returning
+// where the piece was in its piece list
+func (p *Position) clearPiece(sq int) int {
 	if sqOffBoard(sq) {
 		panic(fmt.Sprintf("sq %v is off board", sq))
 	}
@@ -54,6 +55,7 @@ func (p *Position) clearPiece(sq int) {
 	} else {
 		p.pawns[pceMeta.color].clear(SQ64(sq))
 		p.pawns[BOTH].clear(SQ64(sq))
+		p.pawnKey ^= hashPieceKeys[pce][sq]
 	}
 
 	// find where it is in the pieceList
@@ -71,9 +73,21 @@ func (p *Position) clearPiece(sq int) {
 	p.pieceList[pce][p.pieceCount[pce]-1] = 0
 	// decrement the total Piece Count to match
 	p.pieceCount[pce]--
+
+	if p.observer != nil {
+		p.observer.PieceCleared(pce, sq)
+	}
+	return foundPieceIndex
 }
 
+// add a piece to the end of its piece list
 func (p *Position) addPiece(sq int, pce Piece) {
+	p.addPieceAt(sq, pce, p.pieceCount[pce])
+}
+
+// add a piece at a slot in its piece list, moving whatever was there to the end.
+// This reverses a clearPiece that returned the same slot.
+func (p *Position) addPieceAt(sq int, pce Piece, slot int) {
 
 	pceMeta := pieceLookups[pce]
 
@@ -89,14 +103,20 @@ func (p *Position) addPiece(sq int, pce Piece) {
 	} else {
 		p.pawns[pceMeta.color].set(SQ64(sq))
 		p.pawns[BOTH].set(SQ64(sq))
+		p.pawnKey ^= hashPieceKeys[pce][sq]
 	}
 
 	// add value
 	p.materialCount[pceMeta.color] += pceMeta.value
 
 	// update pieceLists
-	p.pieceList[pce][p.pieceCount[pce]] = sq
+	p.pieceList[pce][p.pieceCount[pce]] = p.pieceList[pce][slot]
+	p.pieceList[pce][slot] = sq
 	p.pieceCount[pce]++
+
+	if p.observer != nil {
+		p.observer.PieceAdded(pce, sq)
+	}
 }
 
 func (p *Position) movePiece(from, to int) {
@@ -116,6 +136,7 @@ func (p *Position) movePiece(from, to int) {
 		p.pawns[BOTH].clear(SQ64(from))
 		p.pawns[pceMeta.color].set(SQ64(to))
 		p.pawns[BOTH].set(SQ64(to))
+		p.pawnKey ^= hashPieceKeys[pce][from] ^ hashPieceKeys[pce][to]
 	}
 
 	found := false
@@ -133,6 +154,10 @@ func (p *Position) movePiece(from, to int) {
 		// something setting p.pieceList[PbP][1] to 0
 		panic("didnt find existing Piece")
 	}
+
+	if p.observer != nil {
+		p.observer.PieceMoved(pce, from, to)
+	}
 }
 
 // MakeMove updates the position for a newly made
@@ -159,15 +184,17 @@ func (p *Position) MakeMove(move Movekey) bool {
 		castlePerm: *p.castlePerm,
 	})
 
+	u := &p.history[len(p.history)-1]
+
 	// enPas need to remove an additional Piece
 	if move.isEnPas() {
 		if side == WHITE {
-			p.clearPiece(to - 10)
+			u.capturedSlot = p.clearPiece(to - 10)
 		} else {
 			if p.pieces[to+10] == EMPTY {
 				fmt.Println("woops")
 			}
-			p.clearPiece(to + 10)
+			u.capturedSlot = p.clearPiece(to + 10)
 		}
 	}
 
@@ -237,7 +264,7 @@ func (p *Position) MakeMove(move Movekey) bool {
 
 	// capture Piece
 	if captured != EMPTY {
-		p.clearPiece(to)
+		u.capturedSlot = p.clearPiece(to)
 		p.fiftyMove = 0
 
 		// check castle perms on capture for rooks
@@ -259,7 +286,7 @@ func (p *Position) MakeMove(move Movekey) bool {
 
 	// promoted
 	if promoted != EMPTY {
-		p.clearPiece(to)
+		u.pawnSlot = p.clearPiece(to)
 		p.addPiece(to, promoted)
 	}
 
@@ -319,9 +346,9 @@ func (p *Position) UndoMove() {
 	// enPas need to remove an additional Piece
 	if move.isEnPas() {
 		if p.side == WHITE {
-			p.addPiece(to-10, PbP)
+			p.addPieceAt(to-10, PbP, u.capturedSlot)
 		} else {
-			p.addPiece(to+10, PwP)
+			p.addPieceAt(to+10, PwP, u.capturedSlot)
 		}
 	}
 
@@ -345,9 +372,9 @@ func (p *Position) UndoMove() {
 	if move.getPromoted() != EMPTY {
 		p.clearPiece(to)
 		if pieceLookups[move.getPromoted()].color == WHITE {
-			p.addPiece(to, PwP)
+			p.addPieceAt(to, PwP, u.pawnSlot)
 		} else {
-			p.addPiece(to, PbP)
+			p.addPieceAt(to, PbP, u.pawnSlot)
 		}
 	}
 
@@ -361,7 +388,7 @@ func (p *Position) UndoMove() {
 
 	// restore capture
 	if captured != EMPTY {
-		p.addPiece(to, captured)
+		p.addPieceAt(to, captured, u.capturedSlot)
 	}
 
 	// rehash
@@ -373,3 +400,38 @@ func (p *Position) UndoMove() {
 		panic(err)
 	}
 }
+
+// MakeNullMove passes the turn to the other side without moving anything,
+// used for null move pruning. It shouldn't be made while in check, since
+// the king would be left in check.
+func (p *Position) MakeNullMove() {
+	p.hisPly++
+	p.searchPly++
+	p.history = append(p.history, undo{
+		posKey:     p.posKey,
+		move:       Movekey(0),
+		fiftyMove:  p.fiftyMove,
+		enPas:      p.enPas,
+		castlePerm: *p.castlePerm,
+	})
+
+	// passing gives up any en passant capture
+	p.enPas = NO_SQ
+	p.side ^= 1
+
+	p.posKey = p.GenPosKey()
+}
+
+// UndoNullMove takes back a MakeNullMove
+func (p *Position) UndoNullMove() {
+	p.hisPly--
+	p.searchPly--
+
+	u := p.history[len(p.history)-1]
+	p.history = p.history[:len(p.history)-1]
+
+	p.fiftyMove = u.fiftyMove
+	p.enPas = u.enPas
+	p.side ^= 1
+	p.posKey = u.posKey
+}
@@ -1,6 +1,7 @@
 package position
 
 import (
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"testing"
 )
@@ -37,4 +38,151 @@ func TestPosition_MakeMove(t *testing.T) {
 			require.Len(t, p.history, len(moves)-i-1)
 		}
 	})
+
+	t.Run("undoing a move keeps the piece lists in order", func(t *testing.T) {
+		// the move order comes from the piece lists, so a search
+		// would change if they were shuffled by making moves
+		fens := []string{
+			"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+			"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3",
+			"n1n5/PPPk4/8/8/8/8/4Kppp/5N1N b - - 0 1",
+		}
+
+		for _, fen := range fens {
+			p, err := FromFen(fen)
+			require.Nil(t, err)
+
+			before := p.GetPieceList()
+			for _, mv := range *p.GenerateAllMoves() {
+				if p.MakeMove(mv.Key) {
+					p.UndoMove()
+				}
+				require.Equal(t, before, p.GetPieceList(), mv.Key.ShortString())
+			}
+		}
+	})
+}
+
+func TestPosition_MakeNullMove(t *testing.T) {
+	t.Run("it passes the turn and can be undone", func(t *testing.T) {
+		p, err := FromFen("rnbqkbnr/ppp1pppp/8/3pP3/8/8/PPPP1PPP/RNBQKBNR b KQkq - 0 2")
+		require.Nil(t, err)
+		mv, err := p.ParseMove("f7f5")
+		require.Nil(t, err)
+		p.MakeMove(mv)
+
+		before := p.ToFen()
+		posKey := p.GetPosKey()
+
+		p.MakeNullMove()
+		require.Equal(t, BLACK, p.GetSide())
+		require.Equal(t, NO_SQ, p.enPas)
+		require.Equal(t, p.GenPosKey(), p.GetPosKey())
+		require.NotEqual(t, posKey, p.GetPosKey())
+		require.Equal(t, "rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR b KQkq - 0 3", p.ToFen())
+
+		// the position is still playable after passing
+		mv, err = p.ParseMove("g8f6")
+		require.Nil(t, err)
+		require.True(t, p.MakeMove(mv))
+		p.UndoMove()
+
+		p.UndoNullMove()
+		require.Equal(t, before, p.ToFen())
+		require.Equal(t, posKey, p.GetPosKey())
+	})
+}
+
+func TestPosition_PawnKey(t *testing.T) {
+	t.Run("it follows every kind of pawn move", func(t *testing.T) {
+		// en passant, promotions with and without a capture, and castling
+		p, err := FromFen("r3k2r/1P3ppp/8/3pP3/8/8/5PPP/R3K2R w KQkq d6 0 1")
+		require.Nil(t, err)
+		start := p.GetPawnKey()
+		require.Equal(t, p.GenPawnKey(), start)
+
+		for _, mv := range []string{"e5d6", "b7a8q", "b7b8n", "e1g1", "e1c1", "f2f4", "a1a8"} {
+			key, err := p.ParseMove(mv)
+			require.Nil(t, err, mv)
+			require.True(t, p.MakeMove(key), mv)
+			assert.Equal(t, p.GenPawnKey(), p.GetPawnKey(), mv)
+
+			p.UndoMove()
+			assert.Equal(t, start, p.GetPawnKey(), mv)
+		}
+	})
+
+	t.Run("it only changes when pawns do", func(t *testing.T) {
+		p, err := FromFen("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+		require.Nil(t, err)
+		start := p.GetPawnKey()
+
+		for _, mv := range []string{"g1f3", "g8f6", "b1c3"} {
+			key, err := p.ParseMove(mv)
+			require.Nil(t, err)
+			p.MakeMove(key)
+		}
+		assert.Equal(t, start, p.GetPawnKey())
+
+		key, err := p.ParseMove("e7e5")
+		require.Nil(t, err)
+		p.MakeMove(key)
+		assert.NotEqual(t, start, p.GetPawnKey())
+	})
+}
+
+// boardObserver keeps its own copy of the board from what it's told
+type boardObserver struct {
+	t      *testing.T
+	pieces board120
+}
+
+func (o *boardObserver) PieceAdded(pce Piece, sq int) {
+	assert.Equal(o.t, EMPTY, o.pieces[sq])
+	o.pieces[sq] = pce
+}
+
+func (o *boardObserver) PieceCleared(pce Piece, sq int) {
+	assert.Equal(o.t, pce, o.pieces[sq])
+	o.pieces[sq] = EMPTY
+}
+
+func (o *boardObserver) PieceMoved(pce Piece, from, to int) {
+	assert.Equal(o.t, pce, o.pieces[from])
+	assert.Equal(o.t, EMPTY, o.pieces[to])
+	o.pieces[from] = EMPTY
+	o.pieces[to] = pce
+}
+
+func TestPosition_SetObserver(t *testing.T) {
+	t.Run("it tells the observer about every piece", func(t *testing.T) {
+		// castling both ways, en passant, and promotions with captures
+		p, err := FromFen("r3k2r/1P4p1/8/3pP3/8/8/6p1/R3K2R w KQkq d6 0 1")
+		require.Nil(t, err)
+
+		o := &boardObserver{t: t, pieces: *p.pieces}
+		p.SetObserver(o)
+
+		for _, mv := range []string{"e5d6", "e8g8", "e1c1", "g2h1q", "b7a8n", "h1d1"} {
+			key, err := p.ParseMove(mv)
+			require.Nil(t, err)
+			require.True(t, p.MakeMove(key), mv)
+			assert.Equal(t, *p.pieces, o.pieces, mv)
+		}
+
+		for p.GetHisPly() > 0 {
+			p.UndoMove()
+			assert.Equal(t, *p.pieces, o.pieces)
+		}
+	})
+
+	t.Run("clones and new positions aren't observed", func(t *testing.T) {
+		p, err := FromFen("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+		require.Nil(t, err)
+
+		p.SetObserver(&boardObserver{t: t, pieces: *p.pieces})
+		assert.Nil(t, p.Clone().GetObserver())
+		assert.Nil(t, p.Flip().GetObserver())
+		assert.NotNil(t, p.GetObserver())
+	})
 }
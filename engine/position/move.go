@@ -27,6 +27,9 @@ type Movekey uint64
 
 func (p *Position) ParseMove(str string) (Movekey, error) {
 	mvb := []rune(str)
+	if len(mvb) < 4 {
+		return Movekey(0), fmt.Errorf("str must have at least 4 characters")
+	}
 	if mvb[0] > 'h' || mvb[0] < 'a' {
 		return Movekey(0), fmt.Errorf("str[0] must be a <= x <= h")
 	}
@@ -215,3 +218,29 @@ func (m Movekey) setPromoted(p Piece) Movekey {
 func (m Movekey) IsNoMove() bool {
 	return m == 0
 }
+
+// GetCaptured returns the captured piece, EMPTY for en passant
+// captures since the captured pawn isn't on the to square
+func (m Movekey) GetCaptured() Piece {
+	return m.getCaptured()
+}
+
+// GetPromoted returns the piece a pawn promoted to, or EMPTY
+func (m Movekey) GetPromoted() Piece {
+	return m.getPromoted()
+}
+
+// IsCapture checks for any capture, including en passant
+func (m Movekey) IsCapture() bool {
+	return m.getCaptured() != EMPTY || m.isEnPas()
+}
+
+// GetFrom returns the square the piece is moving from
+func (m Movekey) GetFrom() int {
+	return m.getFrom()
+}
+
+// GetTo returns the square the piece is moving to
+func (m Movekey) GetTo() int {
+	return m.getTo()
+}
@@ -5,7 +5,8 @@ import (
 	"strings"
 )
 
-// todo - kill this
+// Movescore pairs a move with how promising it looks, so
+// the search can try the most likely best moves first
 type Movescore struct {
 	Key   Movekey
 	Score int
@@ -28,65 +29,96 @@ func (list *Movelist) String() string {
 	return b.String()
 }
 
-// todo - clean these up? Might use them to speed up search?
+// CaptureScore is the lowest score given to a capture or promotion, so the
+// search can fit the quiet moves it scores itself underneath
+const CaptureScore = 1000000
+
+// mvvLvaRank orders piece types by value, ignoring color
+var mvvLvaRank = [PIECE_COUNT]int{0, 1, 2, 3, 4, 5, 6, 1, 2, 3, 4, 5, 6}
+
+// mvvLvaScore ranks a capture by Most Valuable Victim, then Least Valuable
+// Attacker. i.e. PxQ is tried before RxQ, and RxQ before PxR
+func mvvLvaScore(victim, attacker Piece) int {
+	return CaptureScore + mvvLvaRank[victim]*10 - mvvLvaRank[attacker]
+}
+
 func (list *Movelist) addQuietMove(move Movekey) {
 	*list = append(*list, &Movescore{move, 0})
 }
 
-func (list *Movelist) addCaptureMove(move Movekey) {
-	*list = append(*list, &Movescore{move, 0})
+func (list *Movelist) addCaptureMove(move Movekey, attacker Piece) {
+	*list = append(*list, &Movescore{move, mvvLvaScore(move.getCaptured(), attacker)})
 }
 
+// addEnPasMove always scores as a pawn capturing a pawn
 func (list *Movelist) addEnPasMove(move Movekey) {
-	*list = append(*list, &Movescore{move, 0})
+	*list = append(*list, &Movescore{move, mvvLvaScore(PwP, PwP)})
+}
+
+// addPromotionMove scores promotions as also capturing the promoted piece
+func (list *Movelist) addPromotionMove(move Movekey, pawn Piece) {
+	score := mvvLvaScore(move.getCaptured(), pawn) + mvvLvaRank[move.getPromoted()]*10
+	*list = append(*list, &Movescore{move, score})
 }
 
 func (list *Movelist) addWhitePawnCaptureMove(from, to int, captured Piece) {
 	if rankLookups[from] == RANK_7 {
-		list.addCaptureMove(Movekey(0).setFrom(from).setTo(to).setCaptured(captured).setPromoted(PwQ))
-		list.addCaptureMove(Movekey(0).setFrom(from).setTo(to).setCaptured(captured).setPromoted(PwR))
-		list.addCaptureMove(Movekey(0).setFrom(from).setTo(to).setCaptured(captured).setPromoted(PwB))
-		list.addCaptureMove(Movekey(0).setFrom(from).setTo(to).setCaptured(captured).setPromoted(PwN))
+		list.addPromotionMove(Movekey(0).setFrom(from).setTo(to).setCaptured(captured).setPromoted(PwQ), PwP)
+		list.addPromotionMove(Movekey(0).setFrom(from).setTo(to).setCaptured(captured).setPromoted(PwR), PwP)
+		list.addPromotionMove(Movekey(0).setFrom(from).setTo(to).setCaptured(captured).setPromoted(PwB), PwP)
+		list.addPromotionMove(Movekey(0).setFrom(from).setTo(to).setCaptured(captured).setPromoted(PwN), PwP)
 	} else {
-		list.addCaptureMove(Movekey(0).setFrom(from).setTo(to).setCaptured(captured))
+		list.addCaptureMove(Movekey(0).setFrom(from).setTo(to).setCaptured(captured), PwP)
 
 	}
 }
 
 func (list *Movelist) addWhitePawnMove(from, to int) {
 	if rankLookups[from] == RANK_7 {
-		list.addCaptureMove(Movekey(0).setFrom(from).setTo(to).setPromoted(PwQ))
-		list.addCaptureMove(Movekey(0).setFrom(from).setTo(to).setPromoted(PwR))
-		list.addCaptureMove(Movekey(0).setFrom(from).setTo(to).setPromoted(PwB))
-		list.addCaptureMove(Movekey(0).setFrom(from).setTo(to).setPromoted(PwN))
+		list.addPromotionMove(Movekey(0).setFrom(from).setTo(to).setPromoted(PwQ), PwP)
+		list.addPromotionMove(Movekey(0).setFrom(from).setTo(to).setPromoted(PwR), PwP)
+		list.addPromotionMove(Movekey(0).setFrom(from).setTo(to).setPromoted(PwB), PwP)
+		list.addPromotionMove(Movekey(0).setFrom(from).setTo(to).setPromoted(PwN), PwP)
 	} else {
-		list.addCaptureMove(Movekey(0).setFrom(from).setTo(to))
+		list.addQuietMove(Movekey(0).setFrom(from).setTo(to))
 	}
 }
 
 func (list *Movelist) addBlackPawnCaptureMove(from, to int, captured Piece) {
 	if rankLookups[from] == RANK_2 {
-		list.addCaptureMove(Movekey(0).setFrom(from).setTo(to).setCaptured(captured).setPromoted(PbQ))
-		list.addCaptureMove(Movekey(0).setFrom(from).setTo(to).setCaptured(captured).setPromoted(PbR))
-		list.addCaptureMove(Movekey(0).setFrom(from).setTo(to).setCaptured(captured).setPromoted(PbB))
-		list.addCaptureMove(Movekey(0).setFrom(from).setTo(to).setCaptured(captured).setPromoted(PbN))
+		list.addPromotionMove(Movekey(0).setFrom(from).setTo(to).setCaptured(captured).setPromoted(PbQ), PbP)
+		list.addPromotionMove(Movekey(0).setFrom(from).setTo(to).setCaptured(captured).setPromoted(PbR), PbP)
+		list.addPromotionMove(Movekey(0).setFrom(from).setTo(to).setCaptured(captured).setPromoted(PbB), PbP)
+		list.addPromotionMove(Movekey(0).setFrom(from).setTo(to).setCaptured(captured).setPromoted(PbN), PbP)
 	} else {
-		list.addCaptureMove(Movekey(0).setFrom(from).setTo(to).setCaptured(captured))
+		list.addCaptureMove(Movekey(0).setFrom(from).setTo(to).setCaptured(captured), PbP)
 	}
 }
 
 func (list *Movelist) addBlackPawnMove(from, to int) {
 	if rankLookups[from] == RANK_2 {
-		list.addCaptureMove(Movekey(0).setFrom(from).setTo(to).setPromoted(PbQ))
-		list.addCaptureMove(Movekey(0).setFrom(from).setTo(to).setPromoted(PbR))
-		list.addCaptureMove(Movekey(0).setFrom(from).setTo(to).setPromoted(PbB))
-		list.addCaptureMove(Movekey(0).setFrom(from).setTo(to).setPromoted(PbN))
+		list.addPromotionMove(Movekey(0).setFrom(from).setTo(to).setPromoted(PbQ), PbP)
+		list.addPromotionMove(Movekey(0).setFrom(from).setTo(to).setPromoted(PbR), PbP)
+		list.addPromotionMove(Movekey(0).setFrom(from).setTo(to).setPromoted(PbB), PbP)
+		list.addPromotionMove(Movekey(0).setFrom(from).setTo(to).setPromoted(PbN), PbP)
 	} else {
-		list.addCaptureMove(Movekey(0).setFrom(from).setTo(to))
+		list.addQuietMove(Movekey(0).setFrom(from).setTo(to))
 	}
 }
 
+// GenerateAllMoves returns every pseudo-legal move, which may
+// still leave the king in check
 func (p *Position) GenerateAllMoves() *Movelist {
+	return p.generateMoves(false)
+}
+
+// GenerateAllCaptures only returns captures, en passant and promotions,
+// used to quickly find the tactical moves in a quiescence search
+func (p *Position) GenerateAllCaptures() *Movelist {
+	return p.generateMoves(true)
+}
+
+func (p *Position) generateMoves(capturesOnly bool) *Movelist {
 
 	list := &Movelist{}
 
@@ -95,43 +127,49 @@ func (p *Position) GenerateAllMoves() *Movelist {
 		panic(fmt.Errorf("p should not be nil"))
 	}
 
-	if p.side == WHITE {
+	// castling is never a capture
+	if !capturesOnly {
+		if p.side == WHITE {
 
-		// white kingside
-		if p.castlePerm.Has(CASTLE_PERMS_WK) {
-			if p.pieces[F1] == EMPTY && p.pieces[G1] == EMPTY {
-				if !p.IsSquareAttacked(E1, BLACK) && !p.IsSquareAttacked(F1, BLACK) && !p.IsSquareAttacked(G1, BLACK) {
-					// add a new castle move
-					list.addQuietMove(Movekey(0).setFrom(E1).setTo(G1).setCastle())
+			// a fen can list castle perms for a king or rook that isn't there,
+			// so castling also needs both of them to be on their starting squares
+
+			// white kingside
+			if p.castlePerm.Has(CASTLE_PERMS_WK) && p.pieces[E1] == PwK && p.pieces[H1] == PwR {
+				if p.pieces[F1] == EMPTY && p.pieces[G1] == EMPTY {
+					if !p.IsSquareAttacked(E1, BLACK) && !p.IsSquareAttacked(F1, BLACK) && !p.IsSquareAttacked(G1, BLACK) {
+						// add a new castle move
+						list.addQuietMove(Movekey(0).setFrom(E1).setTo(G1).setCastle())
+					}
 				}
 			}
-		}
 
-		// white queenside
-		if p.castlePerm.Has(CASTLE_PERMS_WQ) {
-			if p.pieces[D1] == EMPTY && p.pieces[C1] == EMPTY && p.pieces[B1] == EMPTY {
-				if !p.IsSquareAttacked(E1, BLACK) && !p.IsSquareAttacked(D1, BLACK) && !p.IsSquareAttacked(C1, BLACK) {
-					list.addQuietMove(Movekey(0).setFrom(E1).setTo(C1).setCastle())
+			// white queenside
+			if p.castlePerm.Has(CASTLE_PERMS_WQ) && p.pieces[E1] == PwK && p.pieces[A1] == PwR {
+				if p.pieces[D1] == EMPTY && p.pieces[C1] == EMPTY && p.pieces[B1] == EMPTY {
+					if !p.IsSquareAttacked(E1, BLACK) && !p.IsSquareAttacked(D1, BLACK) && !p.IsSquareAttacked(C1, BLACK) {
+						list.addQuietMove(Movekey(0).setFrom(E1).setTo(C1).setCastle())
+					}
 				}
 			}
-		}
 
-	} else {
-		// black kingside
-		if p.castlePerm.Has(CASTLE_PERMS_BK) {
-			if p.pieces[F8] == EMPTY && p.pieces[G8] == EMPTY {
-				if !p.IsSquareAttacked(E8, WHITE) && !p.IsSquareAttacked(F8, WHITE) && !p.IsSquareAttacked(G8, WHITE) {
-					// add a new castle move
-					list.addQuietMove(Movekey(0).setFrom(E8).setTo(G8).setCastle())
+		} else {
+			// black kingside
+			if p.castlePerm.Has(CASTLE_PERMS_BK) && p.pieces[E8] == PbK && p.pieces[H8] == PbR {
+				if p.pieces[F8] == EMPTY && p.pieces[G8] == EMPTY {
+					if !p.IsSquareAttacked(E8, WHITE) && !p.IsSquareAttacked(F8, WHITE) && !p.IsSquareAttacked(G8, WHITE) {
+						// add a new castle move
+						list.addQuietMove(Movekey(0).setFrom(E8).setTo(G8).setCastle())
+					}
 				}
 			}
-		}
 
-		// black queenside
-		if p.castlePerm.Has(CASTLE_PERMS_BQ) {
-			if p.pieces[D8] == EMPTY && p.pieces[C8] == EMPTY && p.pieces[B8] == EMPTY {
-				if !p.IsSquareAttacked(E8, WHITE) && !p.IsSquareAttacked(D8, WHITE) && !p.IsSquareAttacked(C8, WHITE) {
-					list.addQuietMove(Movekey(0).setFrom(E8).setTo(C8).setCastle())
+			// black queenside
+			if p.castlePerm.Has(CASTLE_PERMS_BQ) && p.pieces[E8] == PbK && p.pieces[A8] == PbR {
+				if p.pieces[D8] == EMPTY && p.pieces[C8] == EMPTY && p.pieces[B8] == EMPTY {
+					if !p.IsSquareAttacked(E8, WHITE) && !p.IsSquareAttacked(D8, WHITE) && !p.IsSquareAttacked(C8, WHITE) {
+						list.addQuietMove(Movekey(0).setFrom(E8).setTo(C8).setCastle())
+					}
 				}
 			}
 		}
@@ -147,8 +185,8 @@ func (p *Position) GenerateAllMoves() *Movelist {
 				panic(fmt.Errorf("PwP position off board: %v", sq))
 			}
 
-			// check pawn movements
-			if p.pieces[sq+10] == EMPTY {
+			// check pawn movements, only promotions if we just want captures
+			if p.pieces[sq+10] == EMPTY && (!capturesOnly || rankLookups[sq] == RANK_7) {
 				list.addWhitePawnMove(sq, sq+10)
 
 				// check for RANK_2 double move
@@ -170,10 +208,10 @@ func (p *Position) GenerateAllMoves() *Movelist {
 			// enPas captures
 			if p.enPas != NO_SQ {
 				if lSq == p.enPas {
-					list.addCaptureMove(Movekey(0).setFrom(sq).setTo(lSq).setEnPas())
+					list.addEnPasMove(Movekey(0).setFrom(sq).setTo(lSq).setEnPas())
 				}
 				if rSq == p.enPas {
-					list.addCaptureMove(Movekey(0).setFrom(sq).setTo(rSq).setEnPas())
+					list.addEnPasMove(Movekey(0).setFrom(sq).setTo(rSq).setEnPas())
 				}
 			}
 		}
@@ -186,8 +224,8 @@ func (p *Position) GenerateAllMoves() *Movelist {
 				panic(fmt.Errorf("PbP position off board: %v", sq))
 			}
 
-			// check pawn movements
-			if p.pieces[sq-10] == EMPTY {
+			// check pawn movements, only promotions if we just want captures
+			if p.pieces[sq-10] == EMPTY && (!capturesOnly || rankLookups[sq] == RANK_2) {
 				list.addBlackPawnMove(sq, sq-10)
 
 				// check for RANK_2 double move
@@ -209,10 +247,10 @@ func (p *Position) GenerateAllMoves() *Movelist {
 			// enPas captures
 			if p.enPas != NO_SQ {
 				if lSq == p.enPas {
-					list.addCaptureMove(Movekey(0).setFrom(sq).setTo(lSq).setEnPas())
+					list.addEnPasMove(Movekey(0).setFrom(sq).setTo(lSq).setEnPas())
 				}
 				if rSq == p.enPas {
-					list.addCaptureMove(Movekey(0).setFrom(sq).setTo(rSq).setEnPas())
+					list.addEnPasMove(Movekey(0).setFrom(sq).setTo(rSq).setEnPas())
 				}
 			}
 		}
@@ -240,9 +278,11 @@ func (p *Position) GenerateAllMoves() *Movelist {
 					continue
 				}
 				if p.pieces[toSq] == EMPTY {
-					list.addQuietMove(Movekey(0).setFrom(fromSq).setTo(toSq))
+					if !capturesOnly {
+						list.addQuietMove(Movekey(0).setFrom(fromSq).setTo(toSq))
+					}
 				} else if pieceLookups[p.pieces[toSq]].color != p.side {
-					list.addCaptureMove(Movekey(0).setFrom(fromSq).setTo(toSq).setCaptured(p.pieces[toSq]))
+					list.addCaptureMove(Movekey(0).setFrom(fromSq).setTo(toSq).setCaptured(p.pieces[toSq]), pce)
 				}
 			}
 		}
@@ -258,10 +298,12 @@ func (p *Position) GenerateAllMoves() *Movelist {
 				toPce := p.pieces[toSq]
 				for toPce != NO_SQ {
 					if toPce == EMPTY {
-						list.addQuietMove(Movekey(0).setFrom(fromSq).setTo(toSq))
+						if !capturesOnly {
+							list.addQuietMove(Movekey(0).setFrom(fromSq).setTo(toSq))
+						}
 					} else {
 						if pieceLookups[toPce].color != p.side {
-							list.addCaptureMove(Movekey(0).setFrom(fromSq).setTo(toSq).setCaptured(toPce))
+							list.addCaptureMove(Movekey(0).setFrom(fromSq).setTo(toSq).setCaptured(toPce), pce)
 						}
 						break // go to next direction
 					}
@@ -274,3 +316,18 @@ func (p *Position) GenerateAllMoves() *Movelist {
 
 	return list
 }
+
+// GenerateLegalMoves returns only the moves that don't leave
+// the king of the side to move in check
+func (p *Position) GenerateLegalMoves() *Movelist {
+	list := &Movelist{}
+
+	for _, mv := range *p.GenerateAllMoves() {
+		if p.MakeMove(mv.Key) {
+			p.UndoMove()
+			*list = append(*list, mv)
+		}
+	}
+
+	return list
+}
@@ -1,8 +1,11 @@
 package position
 
 import (
+	"bufio"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"os"
+	"strings"
 	"testing"
 )
 
@@ -85,3 +88,114 @@ func Test_MoveGen_MovelistCount(t *testing.T) {
 		c.assert(t)
 	}
 }
+
+func Test_MoveGen_LegalMoves(t *testing.T) {
+	t.Run("it skips moves leaving the king in check", func(t *testing.T) {
+		p, err := FromFen("4k3/8/8/8/8/8/3r4/4K3 w - - 0 1")
+		require.Nil(t, err)
+
+		assert.Equal(t, 5, len(*p.GenerateAllMoves()))
+		assert.Equal(t, 2, len(*p.GenerateLegalMoves()))
+	})
+}
+
+func Test_MoveGen_Castling(t *testing.T) {
+	castles := func(fen string) []string {
+		p, err := FromFen(fen)
+		require.Nil(t, err)
+
+		names := []string{}
+		for _, mv := range *p.GenerateAllMoves() {
+			if mv.Key.isCastle() {
+				names = append(names, mv.Key.ShortString())
+			}
+		}
+		return names
+	}
+
+	t.Run("it castles with the king and rooks on their starting squares", func(t *testing.T) {
+		assert.ElementsMatch(t, []string{"e1g1", "e1c1"}, castles("r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1"))
+		assert.ElementsMatch(t, []string{"e8g8", "e8c8"}, castles("r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1"))
+	})
+
+	t.Run("it ignores castle perms for a king or rook that isn't there", func(t *testing.T) {
+		assert.Empty(t, castles("1r2k2r/8/8/8/8/8/4K3/R6R w KQkq - 0 1"))
+		assert.Equal(t, []string{"e8g8"}, castles("1r2k2r/8/8/8/8/8/4K3/R6R b KQkq - 0 1"))
+	})
+}
+
+func Test_MoveGen_Captures(t *testing.T) {
+	t.Run("it finds captures, en passant and promotions", func(t *testing.T) {
+		p, err := FromFen("r3k3/1P6/8/3pP3/8/2n5/8/R3K2R w KQq d6 0 1")
+		require.Nil(t, err)
+
+		names := []string{}
+		for _, mv := range *p.GenerateAllCaptures() {
+			names = append(names, mv.Key.ShortString())
+		}
+		assert.ElementsMatch(t, []string{
+			"b7b8q", "b7b8r", "b7b8b", "b7b8n",
+			"b7a8q", "b7a8r", "b7a8b", "b7a8n",
+			"e5d6", "a1a8",
+		}, names)
+	})
+
+	t.Run("it matches the captures from all moves for the perft cases", func(t *testing.T) {
+		file, err := os.Open("../perft/perft_test_cases.txt")
+		require.Nil(t, err)
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			fen := strings.TrimSpace(strings.Split(scanner.Text(), ",")[0]) + " 0 1"
+			p, err := FromFen(fen)
+			require.Nil(t, err)
+
+			want := []Movekey{}
+			for _, mv := range *p.GenerateAllMoves() {
+				if mv.Key.IsCapture() || mv.Key.GetPromoted() != EMPTY {
+					want = append(want, mv.Key)
+				}
+			}
+
+			got := []Movekey{}
+			for _, mv := range *p.GenerateAllCaptures() {
+				got = append(got, mv.Key)
+			}
+			require.ElementsMatch(t, want, got, fen)
+		}
+	})
+}
+
+func Test_MoveGen_Scores(t *testing.T) {
+	t.Run("it scores captures by MVV-LVA", func(t *testing.T) {
+		p, err := FromFen("4k3/8/8/3q4/1r2P3/8/3Q4/4K3 w - - 0 1")
+		require.Nil(t, err)
+
+		scores := map[string]int{}
+		for _, mv := range *p.GenerateAllMoves() {
+			scores[mv.Key.ShortString()] = mv.Score
+		}
+
+		assert.Greater(t, scores["e4d5"], scores["d2d5"], "PxQ before QxQ")
+		assert.Greater(t, scores["d2d5"], scores["d2b4"], "QxQ before QxR")
+		assert.GreaterOrEqual(t, scores["d2b4"], CaptureScore)
+		assert.Equal(t, 0, scores["e4e5"])
+		assert.Equal(t, 0, scores["d2c2"])
+	})
+
+	t.Run("it scores promotions and en passant as captures", func(t *testing.T) {
+		p, err := FromFen("4k3/1P6/8/3pP3/8/8/8/4K3 w - d6 0 1")
+		require.Nil(t, err)
+
+		scores := map[string]int{}
+		for _, mv := range *p.GenerateAllMoves() {
+			scores[mv.Key.ShortString()] = mv.Score
+		}
+
+		assert.GreaterOrEqual(t, scores["e5d6"], CaptureScore)
+		assert.Greater(t, scores["b7b8q"], scores["b7b8n"])
+		assert.Greater(t, scores["b7b8n"], scores["e5d6"])
+		assert.Equal(t, 0, scores["e5e6"])
+	})
+}
@@ -142,6 +142,11 @@ var pieceLookups = map[Piece]pieceMetadata{
 	},
 }
 
+// Value returns the material value of a piece
+func (p Piece) Value() int {
+	return pieceLookups[p].value
+}
+
 func (p Piece) String() string {
 	switch p {
 	case EMPTY:
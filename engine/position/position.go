@@ -13,6 +13,11 @@ type undo struct {
 	enPas      int
 	fiftyMove  int
 	posKey     uint64
+
+	// where the captured piece and promoted pawn were in their piece lists,
+	// so undoing a move puts them back in the same order
+	capturedSlot int
+	pawnSlot     int
 }
 
 // Position is a given state of the board. The pieces field is the source of truth,
@@ -37,7 +42,8 @@ type Position struct {
 
 	searchPly int // how far we are in the search
 
-	posKey uint64 // unique index for position
+	posKey  uint64 // unique index for position
+	pawnKey uint64 // the same for just the pawns, kept up to date as pawns move
 
 	pieceCount    [13]int // Count of all pieces on the board
 	bigPieceCount [2]int  // white/black non-pawn pieces
@@ -48,12 +54,53 @@ type Position struct {
 	// history
 	hisPly  int // how many half Moves have been made in the whole game
 	history []undo
+
+	observer PieceObserver // told about pieces changing, nil if nothing's watching
+}
+
+/*
+PieceObserver is told about every piece added, cleared or moved by MakeMove
+and UndoMove, after the position is updated. It can keep anything worked out
+from the pieces up to date without looking at the whole board, like the
+accumulators of a neural network.
+
+Captures clear the captured piece before the capturing piece moves, and
+promotions clear the pawn before adding the new piece. The king square
+isn't updated until after a king has moved.
+*/
+type PieceObserver interface {
+	PieceAdded(pce Piece, sq int) // 120 based squares
+	PieceCleared(pce Piece, sq int)
+	PieceMoved(pce Piece, from, to int)
+}
+
+// SetObserver starts telling an observer about pieces changing,
+// replacing any there already was. nil stops observing.
+func (p *Position) SetObserver(o PieceObserver) {
+	p.observer = o
+}
+
+// GetObserver returns the observer, nil if there isn't one
+func (p *Position) GetObserver() PieceObserver {
+	return p.observer
 }
 
 func (p *Position) GetPosKey() uint64 {
 	return p.posKey
 }
 
+// GetPawnKey is a key for just the pawns on the board, so anything
+// worked out from the pawns alone can be cached by it
+func (p *Position) GetPawnKey() uint64 {
+	return p.pawnKey
+}
+
+// GetPawns returns the pawn bitboards for white/black/both, where
+// bit 0 is A1 and bit 63 is H8 like the 64 based squares
+func (p *Position) GetPawns() [3]uint64 {
+	return [3]uint64{p.pawns[WHITE].val, p.pawns[BLACK].val, p.pawns[BOTH].val}
+}
+
 func (p *Position) GetSearchPly() int {
 	return p.searchPly
 }
@@ -66,6 +113,11 @@ func (p *Position) GetPieceCount() [13]int {
 	return p.pieceCount
 }
 
+// GetBigPieceCount counts everything but pawns for white/black, including the king
+func (p *Position) GetBigPieceCount() [2]int {
+	return p.bigPieceCount
+}
+
 func (p *Position) GetPieceList() [13][10]int {
 	return p.pieceList
 }
@@ -74,10 +126,48 @@ func (p *Position) GetFiftyMove() int {
 	return p.fiftyMove
 }
 
+// GetKingSq returns the 120 based square of a side's king
+func (p *Position) GetKingSq(color int) int {
+	return p.kingSq[color]
+}
+
 func (p *Position) GetSide() int {
 	return p.side
 }
 
+func (p *Position) GetHisPly() int {
+	return p.hisPly
+}
+
+// GetPiece returns the piece on a 120 based square
+func (p *Position) GetPiece(sq int) Piece {
+	return p.pieces[sq]
+}
+
+// Clone returns a deep copy of the position, including its history, so
+// moves can be made and undone on the copy without affecting the original.
+// The observer isn't copied, since it's watching the original.
+func (p *Position) Clone() *Position {
+	c := *p
+	c.observer = nil
+
+	pieces := *p.pieces
+	c.pieces = &pieces
+
+	for i, bb := range p.pawns {
+		pawns := *bb
+		c.pawns[i] = &pawns
+	}
+
+	castlePerm := *p.castlePerm
+	c.castlePerm = &castlePerm
+
+	c.history = make([]undo, len(p.history), cap(p.history))
+	copy(c.history, p.history)
+
+	return &c
+}
+
 // GenPosKey generates a statistically unique uint64
 // for the current state of the position
 func (p Position) GenPosKey() uint64 {
@@ -108,6 +198,19 @@ func (p Position) GenPosKey() uint64 {
 	return finalKey
 }
 
+// GenPawnKey generates the pawn key from scratch, like GenPosKey but
+// with only the pawns
+func (p Position) GenPawnKey() uint64 {
+	var key uint64 = 0
+	for sq := 0; sq < BOARD_SQ_NUMBER; sq++ {
+		pce := p.pieces[sq]
+		if pce == PwP || pce == PbP {
+			key ^= hashPieceKeys[pce][sq]
+		}
+	}
+	return key
+}
+
 // updateListCaches updates all piece caches based on pieces
 func (p *Position) updateListCaches() {
 	for i := 0; i < BOARD_SQ_NUMBER; i++ {
@@ -261,6 +364,9 @@ func (p *Position) AssertCache() error {
 	if !reflect.DeepEqual(t_pawns, p.pawns) {
 		return fmt.Errorf("pawns - got %v want %v", p.pawns, t_pawns)
 	}
+	if p.pawnKey != p.GenPawnKey() {
+		return fmt.Errorf("pawnKey - got %v want %v", p.pawnKey, p.GenPawnKey())
+	}
 	if p.posKey != t_posKey {
 		return fmt.Errorf("posKey - got %v want %v", p.posKey, t_posKey)
 	}
@@ -308,6 +414,8 @@ func (p *Position) Reset() {
 	p.hisPly = 0
 	p.history = []undo{}
 	p.posKey = 0
+	p.pawnKey = 0
+	p.observer = nil
 }
 
 func (p *Position) IsSquareAttacked(sq, attackingColor int) bool {
@@ -469,8 +577,15 @@ func (p Position) PrintAttackBoard(attackingSide int) string {
 	return output.String()
 }
 
+// IsRepetition reports whether the position came up earlier in the game.
+// Only positions since the last capture or pawn move can repeat, and the scan
+// stops at a null move, since positions before a pass weren't really reached.
 func (p *Position) IsRepetition() bool {
-	for _, his := range p.history {
+	for i := len(p.history) - 1; i >= 0 && i >= len(p.history)-p.fiftyMove; i-- {
+		his := p.history[i]
+		if his.move.IsNoMove() {
+			return false
+		}
 		if his.posKey == p.posKey {
 			return true
 		}
@@ -3,6 +3,7 @@ package position
 import (
 	"fmt"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"testing"
 )
 
@@ -164,3 +165,68 @@ func TestAssertCache(t *testing.T) {
 
 	assert.Nil(t, state.AssertCache())
 }
+
+func TestPosition_Clone(t *testing.T) {
+	t.Run("it doesn't share state with the original", func(t *testing.T) {
+		p, err := FromFen("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+		assert.Nil(t, err)
+		mv, err := p.ParseMove("e1g1")
+		assert.Nil(t, err)
+		p.MakeMove(mv)
+
+		before := p.ToFen()
+		c := p.Clone()
+		assert.Equal(t, before, c.ToFen())
+		assert.Equal(t, p.GetPosKey(), c.GetPosKey())
+
+		for _, mv := range *c.GenerateLegalMoves() {
+			c.MakeMove(mv.Key)
+			assert.Nil(t, c.AssertCache())
+			assert.Equal(t, before, p.ToFen())
+			assert.Nil(t, p.AssertCache())
+			c.UndoMove()
+		}
+
+		// both can undo the move made before cloning
+		c.UndoMove()
+		assert.Equal(t, before, p.ToFen())
+		p.UndoMove()
+		assert.Equal(t, c.ToFen(), p.ToFen())
+	})
+}
+
+func TestPosition_IsRepetition(t *testing.T) {
+	play := func(p *Position, moves ...string) {
+		for _, mv := range moves {
+			key, err := p.ParseMove(mv)
+			require.Nil(t, err)
+			require.True(t, p.MakeMove(key))
+		}
+	}
+
+	t.Run("it finds a position played before", func(t *testing.T) {
+		p, err := FromFen("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+		require.Nil(t, err)
+		play(p, "g1f3", "g8f6", "f3g1")
+		assert.False(t, p.IsRepetition())
+		play(p, "f6g8")
+		assert.True(t, p.IsRepetition())
+	})
+
+	t.Run("it ignores positions before a null move", func(t *testing.T) {
+		p, err := FromFen("4k3/8/8/8/8/8/8/4K3 w - - 10 20")
+		require.Nil(t, err)
+
+		// passing twice brings back the starting position,
+		// but it wasn't reached by moves that were really played
+		play(p, "e1d1")
+		p.MakeNullMove()
+		play(p, "d1e1")
+		p.MakeNullMove()
+		assert.False(t, p.IsRepetition())
+
+		p.UndoNullMove()
+		play(p, "e8d8", "e1d1", "d8e8")
+		assert.True(t, p.IsRepetition())
+	})
+}
@@ -0,0 +1,210 @@
+package position
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+Standard Algebraic Notation (SAN) is the move format used by
+PGN files and most chess players. Instead of the from/to squares
+it lists the piece type and the destination square, with just
+enough information added to make the move unique.
+
+- e4, exd5, e8=Q - pawn moves, captures and promotions
+- Nf3, Nbd7, R1e2, Qh4xe1 - pieces, with file/rank disambiguation
+- O-O, O-O-O - king and queenside castling
+- Qxf7# Bb5+ - check and checkmate suffixes
+*/
+
+// sanLetter returns the uppercase piece letter used by SAN,
+// ignoring the color of the piece
+func (pce Piece) sanLetter() byte {
+	return strings.ToUpper(pce.String())[0]
+}
+
+// ToSAN returns the SAN string for a move in the current position.
+// The move is expected to be legal, as disambiguation and check
+// suffixes are worked out from the legal moves.
+func (p *Position) ToSAN(mv Movekey) string {
+	from := mv.getFrom()
+	to := mv.getTo()
+	pce := p.pieces[from]
+	capture := mv.getCaptured() != EMPTY || mv.isEnPas()
+
+	b := strings.Builder{}
+
+	if mv.isCastle() {
+		if fileLookups[to] == FILE_G {
+			b.WriteString("O-O")
+		} else {
+			b.WriteString("O-O-O")
+		}
+	} else if !pieceLookups[pce].isBig {
+		// pawns only mention the file when capturing
+		if capture {
+			b.WriteByte(byte('a' + fileLookups[from]))
+			b.WriteString("x")
+		}
+		b.WriteString(printSq(to))
+		if mv.getPromoted() != EMPTY {
+			b.WriteString("=")
+			b.WriteByte(mv.getPromoted().sanLetter())
+		}
+	} else {
+		b.WriteByte(pce.sanLetter())
+
+		// check if another piece of the same type can reach the same square
+		ambiguous, sameFile, sameRank := false, false, false
+		for _, other := range *p.GenerateLegalMoves() {
+			otherFrom := other.Key.getFrom()
+			if other.Key.getTo() != to || otherFrom == from || p.pieces[otherFrom] != pce {
+				continue
+			}
+			ambiguous = true
+			if fileLookups[otherFrom] == fileLookups[from] {
+				sameFile = true
+			}
+			if rankLookups[otherFrom] == rankLookups[from] {
+				sameRank = true
+			}
+		}
+
+		// prefer the file, then the rank, then both
+		if ambiguous {
+			if !sameFile {
+				b.WriteByte(byte('a' + fileLookups[from]))
+			} else if !sameRank {
+				b.WriteByte(byte('1' + rankLookups[from]))
+			} else {
+				b.WriteString(printSq(from))
+			}
+		}
+
+		if capture {
+			b.WriteString("x")
+		}
+		b.WriteString(printSq(to))
+	}
+
+	// check/checkmate suffixes
+	if p.MakeMove(mv) {
+		if p.IsKingAttacked() {
+			if p.IsLegalMove() {
+				b.WriteString("+")
+			} else {
+				b.WriteString("#")
+			}
+		}
+		p.UndoMove()
+	}
+
+	return b.String()
+}
+
+// ParseSAN finds the legal move matching a SAN string. Check, mate
+// and annotation suffixes are ignored, as is an "e.p." marker.
+func (p *Position) ParseSAN(str string) (Movekey, error) {
+	san := strings.TrimSpace(str)
+	san = strings.TrimSpace(strings.TrimSuffix(san, "e.p."))
+	san = strings.TrimRight(san, "+#!?")
+	if san == "" {
+		return Movekey(0), fmt.Errorf("empty move")
+	}
+
+	legal := p.GenerateLegalMoves()
+
+	// castling
+	castleFile := NO_SQ
+	switch san {
+	case "O-O", "0-0":
+		castleFile = FILE_G
+	case "O-O-O", "0-0-0":
+		castleFile = FILE_C
+	}
+	if castleFile != NO_SQ {
+		for _, mv := range *legal {
+			if mv.Key.isCastle() && fileLookups[mv.Key.getTo()] == castleFile {
+				return mv.Key, nil
+			}
+		}
+		return Movekey(0), fmt.Errorf("castle %q is not legal", str)
+	}
+
+	// piece type, pawns have no letter
+	pceLetter := byte('P')
+	if strings.IndexByte("NBRQK", san[0]) >= 0 {
+		pceLetter = san[0]
+		san = san[1:]
+	}
+
+	// promotion, either e8=Q or e8Q
+	promLetter := byte(0)
+	if i := strings.IndexByte(san, '='); i >= 0 {
+		if i+1 >= len(san) {
+			return Movekey(0), fmt.Errorf("missing promotion piece in %q", str)
+		}
+		promLetter = san[i+1]
+		san = san[:i]
+	} else if pceLetter == 'P' && len(san) > 0 && strings.IndexByte("NBRQ", san[len(san)-1]) >= 0 {
+		promLetter = san[len(san)-1]
+		san = san[:len(san)-1]
+	}
+
+	// destination square
+	if len(san) < 2 {
+		return Movekey(0), fmt.Errorf("could not find destination square in %q", str)
+	}
+	toFile := int(san[len(san)-2]) - 'a'
+	toRank := int(san[len(san)-1]) - '1'
+	if toFile < FILE_A || toFile > FILE_H || toRank < RANK_1 || toRank > RANK_8 {
+		return Movekey(0), fmt.Errorf("invalid destination square in %q", str)
+	}
+	to := fileRankToSq(toFile, toRank)
+
+	// anything left over is disambiguation or the capture marker
+	fromFile, fromRank := NO_SQ, NO_SQ
+	for _, c := range san[:len(san)-2] {
+		switch {
+		case c == 'x':
+		case c >= 'a' && c <= 'h':
+			fromFile = int(c - 'a')
+		case c >= '1' && c <= '8':
+			fromRank = int(c - '1')
+		default:
+			return Movekey(0), fmt.Errorf("unexpected character %q in %q", c, str)
+		}
+	}
+
+	found := Movekey(0)
+	matches := 0
+	for _, mv := range *legal {
+		from := mv.Key.getFrom()
+		if mv.Key.getTo() != to || p.pieces[from].sanLetter() != pceLetter {
+			continue
+		}
+		if fromFile != NO_SQ && fileLookups[from] != fromFile {
+			continue
+		}
+		if fromRank != NO_SQ && rankLookups[from] != fromRank {
+			continue
+		}
+		if mv.Key.getPromoted() != EMPTY {
+			if mv.Key.getPromoted().sanLetter() != promLetter {
+				continue
+			}
+		} else if promLetter != 0 {
+			continue
+		}
+		found = mv.Key
+		matches++
+	}
+
+	if matches == 0 {
+		return Movekey(0), fmt.Errorf("no legal move matches %q", str)
+	}
+	if matches > 1 {
+		return Movekey(0), fmt.Errorf("move %q is ambiguous", str)
+	}
+	return found, nil
+}
@@ -0,0 +1,128 @@
+package position
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type testCaseSAN struct {
+	name  string
+	fen   string
+	move  string // uci style move
+	san   string // expected SAN output
+	input string // optional alternate SAN input
+}
+
+func (tc testCaseSAN) assert(t *testing.T) {
+	t.Helper()
+	p, err := FromFen(tc.fen)
+	require.Nil(t, err)
+
+	mv, err := p.ParseMove(tc.move)
+	require.Nil(t, err)
+	require.False(t, mv.IsNoMove(), tc.name)
+
+	assert.Equal(t, tc.san, p.ToSAN(mv), tc.name)
+
+	input := tc.input
+	if input == "" {
+		input = tc.san
+	}
+	parsed, err := p.ParseSAN(input)
+	require.Nil(t, err, tc.name)
+	assert.Equal(t, mv, parsed, tc.name)
+}
+
+func Test_SAN(t *testing.T) {
+	cases := []testCaseSAN{
+		{
+			name: "pawn push",
+			fen:  "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			move: "e2e4",
+			san:  "e4",
+		},
+		{
+			name: "knight move",
+			fen:  "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			move: "g1f3",
+			san:  "Nf3",
+		},
+		{
+			name: "pawn capture",
+			fen:  "rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 2",
+			move: "e4d5",
+			san:  "exd5",
+		},
+		{
+			name:  "en passant",
+			fen:   "rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 3",
+			move:  "e5d6",
+			san:   "exd6",
+			input: "exd6 e.p.",
+		},
+		{
+			name: "file disambiguation",
+			fen:  "rnbqkb1r/ppp1pppp/5n2/3p4/8/8/PPPPPPPP/RNBQKBNR b KQkq - 0 1",
+			move: "b8d7",
+			san:  "Nbd7",
+		},
+		{
+			name: "rank disambiguation",
+			fen:  "4k3/8/8/R7/8/8/8/R3K3 w - - 0 1",
+			move: "a1a3",
+			san:  "R1a3",
+		},
+		{
+			name: "square disambiguation",
+			fen:  "8/8/1k6/8/4Q2Q/8/8/K6Q w - - 0 1",
+			move: "h4e1",
+			san:  "Qh4e1",
+		},
+		{
+			name: "king side castle",
+			fen:  "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1",
+			move: "e1g1",
+			san:  "O-O",
+		},
+		{
+			name: "queen side castle",
+			fen:  "r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1",
+			move: "e8c8",
+			san:  "O-O-O",
+		},
+		{
+			name:  "promotion with check",
+			fen:   "7k/4P3/8/8/8/8/8/K7 w - - 0 1",
+			move:  "e7e8q",
+			san:   "e8=Q+",
+			input: "e8Q",
+		},
+		{
+			name: "under promotion",
+			fen:  "7k/4P3/8/8/8/8/8/K7 w - - 0 1",
+			move: "e7e8n",
+			san:  "e8=N",
+		},
+		{
+			name: "checkmate",
+			fen:  "k7/7Q/K7/8/8/8/8/8 w - - 0 1",
+			move: "h7b7",
+			san:  "Qb7#",
+		},
+	}
+
+	for _, tc := range cases {
+		tc.assert(t)
+	}
+}
+
+func Test_ParseSAN_Errors(t *testing.T) {
+	p, err := FromFen("4k3/8/8/8/7Q/8/8/4K2Q w - - 0 1")
+	require.Nil(t, err)
+
+	for _, san := range []string{"", "Nf3", "Qe1", "e9", "O-O", "Qz4"} {
+		_, err := p.ParseSAN(san)
+		assert.NotNil(t, err, san)
+	}
+}
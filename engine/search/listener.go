@@ -0,0 +1,70 @@
+package search
+
+import (
+	"cacti-chess/engine/eval"
+	"cacti-chess/engine/position"
+	"time"
+)
+
+// Info is the progress of a search, sent to the Listener after each depth
+type Info struct {
+	Depth    int
+	SelDepth int        // the deepest ply reached, including the quiescence search
+	Score    eval.Score // centipawns, relative to the side to move
+	Mate     int        // moves until mate, negative when getting mated, if IsMate
+	IsMate   bool       // the score is a forced mate, which can be mate in 0
+	Nodes    uint64     // summed over every thread
+	NPS      uint64
+	Time     time.Duration
+	HashFull int // permille of the hash table used by this search
+	PV       []position.Movekey
+
+	Ordering float64 // fraction of beta cutoffs on the first move, see moveOrdering
+}
+
+// Listener is told about the progress of a search. It's called from
+// the search goroutine, between depths, with the position at the root.
+type Listener interface {
+	OnInfo(info Info)
+}
+
+// ListenerFunc lets a plain function be used as a Listener
+type ListenerFunc func(info Info)
+
+func (f ListenerFunc) OnInfo(info Info) {
+	f(info)
+}
+
+// SetListener sets who is told about the progress of searches, nil for no one
+func (s *SearchInfo) SetListener(l Listener) {
+	s.listener = l
+}
+
+// report sends the result of a finished depth to the listener
+func (s *SearchInfo) report(score eval.Score, line []position.Movekey) {
+	if s.listener == nil || s.isHelper {
+		return
+	}
+
+	elapsed := time.Since(s.start)
+	nodes := s.totalNodes()
+	nps := uint64(0)
+	if elapsed > 0 {
+		nps = uint64(float64(nodes) / elapsed.Seconds())
+	}
+
+	mate, isMate := score.MateMoves()
+	s.listener.OnInfo(Info{
+		Depth:    s.depth,
+		SelDepth: s.selDepth,
+		Score:    score,
+		Mate:     mate,
+		IsMate:   isMate,
+		Nodes:    nodes,
+		NPS:      nps,
+		Time:     elapsed,
+		HashFull: s.tt.HashFull(),
+		PV:       line,
+		Ordering: s.moveOrdering(),
+	})
+}
@@ -0,0 +1,40 @@
+package search
+
+import (
+	"cacti-chess/engine/position"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSearchInfo_SetListener(t *testing.T) {
+	t.Run("it reports each finished depth", func(t *testing.T) {
+		p, err := position.FromFen("r5rk/5p1p/5R2/4B3/8/8/7P/7K w - - 0 1")
+		require.Nil(t, err)
+
+		infos := []Info{}
+		s := New()
+		s.SetHashSize(1)
+		s.SetListener(ListenerFunc(func(info Info) {
+			infos = append(infos, info)
+		}))
+		_, line := s.SearchPosition(p, Options{Depth: 6})
+
+		require.Len(t, infos, 6)
+		for i, info := range infos {
+			assert.Equal(t, i+1, info.Depth)
+			assert.GreaterOrEqual(t, info.SelDepth, info.Depth)
+			assert.NotEmpty(t, info.PV)
+		}
+
+		last := infos[len(infos)-1]
+		assert.Equal(t, line, last.PV)
+		assert.Equal(t, 3, last.Mate)
+		assert.True(t, last.IsMate)
+		assert.Equal(t, s.nodes, last.Nodes)
+		assert.Greater(t, last.HashFull, 0)
+		assert.Equal(t, s.moveOrdering(), last.Ordering)
+		assert.Greater(t, last.Ordering, 0.0)
+		assert.LessOrEqual(t, last.Ordering, 1.0)
+	})
+}
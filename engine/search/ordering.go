@@ -0,0 +1,89 @@
+package search
+
+import "cacti-chess/engine/position"
+
+// Move ordering scores. Captures and promotions are already scored from
+// position.CaptureScore by the move generator using MVV-LVA, so everything
+// else is placed around them.
+const (
+	hashMoveScore = position.CaptureScore * 2      // best move from a previous search
+	killerScore1  = position.CaptureScore - 100000 // most recent quiet cutoff at this ply
+	killerScore2  = position.CaptureScore - 200000 // the cutoff before that
+	historyMax    = killerScore2 - 1               // history is kept under the killers
+)
+
+/*
+scoreMoves sets the ordering score for each move, so the moves most likely to
+be best are searched first. The sooner a good move is found, the more of the
+remaining moves alpha/beta can cut off.
+
+The order is
+  - the hash move, the best move the last time this position was searched
+  - captures, most valuable victim first
+  - killers, quiet moves that caused a cutoff at the same ply in a sibling node
+  - every other quiet move, by how often it has raised alpha elsewhere
+*/
+func (s *SearchInfo) scoreMoves(p *position.Position, list *position.Movelist, hashMove position.Movekey) {
+	for _, mv := range *list {
+		switch {
+		case !hashMove.IsNoMove() && mv.Key == hashMove:
+			mv.Score = hashMoveScore
+		case !isQuiet(mv.Key):
+			// scored by the move generator
+		case mv.Key == s.searchKillers[0][s.searchPly]:
+			mv.Score = killerScore1
+		case mv.Key == s.searchKillers[1][s.searchPly]:
+			mv.Score = killerScore2
+		default:
+			mv.Score = s.searchHistory[p.GetPiece(mv.Key.GetFrom())][mv.Key.GetTo()]
+		}
+	}
+}
+
+// pickNextMove swaps the highest scoring move from i onwards into i. This is
+// cheaper than sorting since a cutoff usually happens in the first few moves.
+func pickNextMove(list *position.Movelist, i int) {
+	moves := *list
+	best := i
+	for j := i + 1; j < len(moves); j++ {
+		if moves[j].Score > moves[best].Score {
+			best = j
+		}
+	}
+	moves[i], moves[best] = moves[best], moves[i]
+}
+
+// storeKiller saves a quiet move that caused a beta cutoff at the current ply
+func (s *SearchInfo) storeKiller(mv position.Movekey) {
+	if s.searchKillers[0][s.searchPly] == mv {
+		return
+	}
+	s.searchKillers[1][s.searchPly] = s.searchKillers[0][s.searchPly]
+	s.searchKillers[0][s.searchPly] = mv
+}
+
+// isKiller is if a move is one of the killers at the current ply
+func (s *SearchInfo) isKiller(mv position.Movekey) bool {
+	return s.searchKillers[0][s.searchPly] == mv || s.searchKillers[1][s.searchPly] == mv
+}
+
+// storeHistory rewards a quiet move that raised alpha or caused a beta
+// cutoff, with deeper searches counting for more. Once a score gets too large every
+// entry is halved, so they stay below the killers.
+func (s *SearchInfo) storeHistory(p *position.Position, mv position.Movekey, depth int) {
+	pce := p.GetPiece(mv.GetFrom())
+	s.searchHistory[pce][mv.GetTo()] += depth * depth
+
+	if s.searchHistory[pce][mv.GetTo()] > historyMax {
+		for i := range s.searchHistory {
+			for j := range s.searchHistory[i] {
+				s.searchHistory[i][j] /= 2
+			}
+		}
+	}
+}
+
+// isQuiet is true for any move that doesn't capture or promote
+func isQuiet(mv position.Movekey) bool {
+	return !mv.IsCapture() && mv.GetPromoted() == position.EMPTY
+}
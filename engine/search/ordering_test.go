@@ -0,0 +1,89 @@
+package search
+
+import (
+	"cacti-chess/engine/eval"
+	"cacti-chess/engine/position"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSearchInfo_scoreMoves(t *testing.T) {
+	t.Run("it orders hash move, captures, killers then history", func(t *testing.T) {
+		p, err := position.FromFen("4k3/8/8/3q4/4P3/8/3Q4/4K3 w - - 0 1")
+		require.Nil(t, err)
+
+		parse := func(str string) position.Movekey {
+			mv, err := p.ParseMove(str)
+			require.Nil(t, err)
+			return mv
+		}
+
+		s := New()
+		s.storeKiller(parse("d2a5"))
+		s.storeKiller(parse("d2h6"))
+		s.storeHistory(p, parse("e1f1"), 3)
+
+		movelist := p.GenerateAllMoves()
+		s.scoreMoves(p, movelist, parse("d2d3"))
+
+		names := []string{}
+		for i := range *movelist {
+			pickNextMove(movelist, i)
+			names = append(names, (*movelist)[i].Key.ShortString())
+		}
+		assert.Equal(t, []string{"d2d3", "e4d5", "d2d5", "d2h6", "d2a5", "e1f1"}, names[:6])
+	})
+}
+
+func TestSearchInfo_storeKiller(t *testing.T) {
+	t.Run("it keeps the two most recent killers per ply", func(t *testing.T) {
+		s := New()
+		s.searchPly = 3
+
+		s.storeKiller(position.Movekey(1))
+		s.storeKiller(position.Movekey(2))
+		s.storeKiller(position.Movekey(2))
+		assert.Equal(t, position.Movekey(2), s.searchKillers[0][3])
+		assert.Equal(t, position.Movekey(1), s.searchKillers[1][3])
+
+		s.storeKiller(position.Movekey(3))
+		assert.Equal(t, position.Movekey(3), s.searchKillers[0][3])
+		assert.Equal(t, position.Movekey(2), s.searchKillers[1][3])
+		assert.True(t, s.searchKillers[0][2].IsNoMove())
+	})
+}
+
+func TestSearchInfo_storeHistory(t *testing.T) {
+	t.Run("it rewards quiet moves that cause a cutoff", func(t *testing.T) {
+		p, err := position.FromFen("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+		require.Nil(t, err)
+
+		// a null window far below the score, so the first move cuts
+		// off without raising alpha, as it would in a pvs search
+		params := DefaultParams()
+		params.ReverseFutility = false
+		s := New()
+		s.SetParams(params)
+		score := s.AlphaBeta(p, -1000, -999, 2, false)
+		assert.Equal(t, eval.Score(-999), score)
+
+		mv := s.searchKillers[0][0]
+		require.False(t, mv.IsNoMove())
+		assert.Equal(t, 2*2, s.searchHistory[p.GetPiece(mv.GetFrom())][mv.GetTo()])
+	})
+
+	t.Run("it stays below the killer scores", func(t *testing.T) {
+		p, err := position.FromFen("4k3/8/8/8/8/8/8/4K3 w - - 0 1")
+		require.Nil(t, err)
+		mv, err := p.ParseMove("e1e2")
+		require.Nil(t, err)
+
+		s := New()
+		for i := 0; i < 100000; i++ {
+			s.storeHistory(p, mv, 10)
+		}
+		assert.LessOrEqual(t, s.searchHistory[position.PwK][mv.GetTo()], historyMax)
+		assert.Greater(t, s.searchHistory[position.PwK][mv.GetTo()], 0)
+	})
+}
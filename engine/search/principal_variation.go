@@ -2,42 +2,38 @@ package search
 
 import "cacti-chess/engine/position"
 
-// PrincipalVariationTable can be used to reconstruct the best
-// line found from a position. As the AlphaBeta function evaluates,
-// it stores key/values for the poskey of a position with the best known
-// move. The "principal variation" is the best known line for a given position
-type PrincipalVariationTable map[uint64]position.Movekey
-
-// Set adds a new movekey, retrieving the poskey from the position
-func (tb *PrincipalVariationTable) Set(p *position.Position, mv position.Movekey) {
-	(*tb)[p.GetPosKey()] = mv
+/*
+PrincipalVariation collects the best line found by AlphaBeta, the "principal
+variation", straight from the search stack. It's a triangular array, where
+row ply holds the best line from the node at that ply onwards.
+
+Each node starts with an empty line. When a move raises alpha, it becomes
+the first move of the node's line, followed by the line its child just
+found. Lines only ever grow from moves that were searched from the node, so
+the line at the root is always legal, and never longer than the deepest ply.
+*/
+type PrincipalVariation struct {
+	length [maxDepth + 2]int // where each row's line ends, starting at the ply
+	moves  [maxDepth + 2][maxDepth + 2]position.Movekey
 }
 
-// Probe gets the movekey from a given position
-func (tb *PrincipalVariationTable) Probe(p *position.Position) position.Movekey {
-	return (*tb)[p.GetPosKey()]
+// clear empties the line at a ply, when a node is entered
+func (pv *PrincipalVariation) clear(ply int) {
+	pv.length[ply] = ply
 }
 
-// GetBestLine returns the best known line as a slice of movekeys. It will also
-// undo all the moves back to before the alpha/beta started.
-func (tb PrincipalVariationTable) GetBestLine(p *position.Position) []position.Movekey {
-	var moves []position.Movekey
-
-	move := tb[p.GetPosKey()]
-
-	for move != position.Movekey(0) {
-		if p.MoveExists(move) {
-			moves = append(moves, move)
-			p.MakeMove(move)
-			move = tb[p.GetPosKey()]
-		} else {
-			panic("move does not exist!")
-		}
-	}
-
-	for i := 0; i < len(moves); i++ {
-		p.UndoMove()
-	}
+// update starts the line at a ply with a move, followed by the line
+// found from the position after it
+func (pv *PrincipalVariation) update(ply int, mv position.Movekey) {
+	pv.moves[ply][ply] = mv
+	next := pv.length[ply+1]
+	copy(pv.moves[ply][ply+1:next], pv.moves[ply+1][ply+1:next])
+	pv.length[ply] = next
+}
 
-	return moves
+// Line returns a copy of the best line from the root
+func (pv *PrincipalVariation) Line() []position.Movekey {
+	line := make([]position.Movekey, pv.length[0])
+	copy(line, pv.moves[0][:pv.length[0]])
+	return line
 }
@@ -0,0 +1,86 @@
+package search
+
+import (
+	"cacti-chess/engine/eval"
+	"cacti-chess/engine/position"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestPrincipalVariation(t *testing.T) {
+	t.Run("it builds the line from each ply's best move", func(t *testing.T) {
+		pv := &PrincipalVariation{}
+		a, b, c := position.Movekey(1), position.Movekey(2), position.Movekey(3)
+
+		// the deepest node finishes first
+		pv.clear(0)
+		pv.clear(1)
+		pv.clear(2)
+		pv.clear(3)
+		pv.update(2, c)
+		pv.update(1, b)
+		pv.update(0, a)
+		assert.Equal(t, []position.Movekey{a, b, c}, pv.Line())
+
+		// a better move at ply 1 replaces everything after it
+		pv.clear(2)
+		pv.update(1, c)
+		pv.update(0, a)
+		assert.Equal(t, []position.Movekey{a, c}, pv.Line())
+	})
+
+	t.Run("it returns a copy", func(t *testing.T) {
+		pv := &PrincipalVariation{}
+		pv.clear(1)
+		pv.update(0, position.Movekey(1))
+
+		line := pv.Line()
+		line[0] = position.Movekey(2)
+		assert.Equal(t, []position.Movekey{1}, pv.Line())
+	})
+}
+
+func TestSearchInfo_SearchPosition_line(t *testing.T) {
+	t.Run("every line is legal", func(t *testing.T) {
+		for _, fen := range benchPositions {
+			p, err := position.FromFen(fen)
+			require.Nil(t, err)
+
+			s := New()
+			s.SetListener(ListenerFunc(func(info Info) {
+				require.NotEmpty(t, info.PV)
+				for _, mv := range info.PV {
+					require.True(t, p.MoveExists(mv), fen)
+					p.MakeMove(mv)
+				}
+				for range info.PV {
+					p.UndoMove()
+				}
+			}))
+			s.SearchPosition(p, Options{Depth: 6})
+		}
+	})
+
+	type testCase struct {
+		name  string
+		fen   string
+		score eval.Score
+	}
+
+	for _, tc := range []testCase{
+		{"checkmate", "R5k1/5ppp/8/8/8/8/8/6K1 b - - 0 1", eval.MatedIn(0)},
+		{"stalemate", "7k/5Q2/6K1/8/8/8/8/8 b - - 0 1", 0},
+	} {
+		t.Run("it returns an empty line for "+tc.name, func(t *testing.T) {
+			p, err := position.FromFen(tc.fen)
+			require.Nil(t, err)
+
+			s := New()
+			score, line := s.SearchPosition(p, Options{Depth: 6})
+			assert.Equal(t, tc.score, score)
+			assert.Empty(t, line)
+			assert.Equal(t, 1, s.depth)
+		})
+	}
+}
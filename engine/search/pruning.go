@@ -0,0 +1,220 @@
+package search
+
+import (
+	"cacti-chess/engine/eval"
+	"cacti-chess/engine/position"
+	"math"
+)
+
+// lmrMaxMoves is the size of the reduction table, later moves share the last entry
+const lmrMaxMoves = 64
+
+/*
+Params are the tunable constants of the selective search, the parts that
+decide which moves don't need a full search and which need a deeper one.
+Every technique has its own switch, so it can be A/B tested against the
+same search without it.
+
+Depths are in plies and margins in centipawns.
+*/
+type Params struct {
+	// late move reductions, see lateMoveReduction
+	LMR         bool
+	LMRMinDepth int     // only reduce with at least this much depth left
+	LMRMinMoves int     // moves searched at full depth before reducing
+	LMRBase     float64 // reduction = base + ln(depth) * ln(move) / divisor
+	LMRDivisor  float64
+
+	// reverse futility pruning, fail high when the static eval is
+	// margin*depth above beta
+	ReverseFutility       bool
+	ReverseFutilityDepth  int
+	ReverseFutilityMargin int
+
+	// razoring, drop into the quiescence search when the static eval
+	// is margin*depth below alpha
+	Razoring       bool
+	RazoringDepth  int
+	RazoringMargin int
+
+	// futility pruning, skip quiet moves when the static eval is
+	// margin*depth below alpha
+	Futility       bool
+	FutilityDepth  int
+	FutilityMargin int
+
+	// extensions, see extension
+	CheckExtension       bool // search a ply deeper when in check
+	SingleReplyExtension bool // or when there's only one way out of check
+
+	// mate distance pruning, see mateDistancePrune
+	MateDistance bool
+}
+
+// DefaultParams are the values the engine plays with
+func DefaultParams() Params {
+	return Params{
+		LMR:         true,
+		LMRMinDepth: 3,
+		LMRMinMoves: 3,
+		LMRBase:     0.75,
+		LMRDivisor:  2.25,
+
+		ReverseFutility:       true,
+		ReverseFutilityDepth:  6,
+		ReverseFutilityMargin: 90,
+
+		Razoring:       true,
+		RazoringDepth:  1,
+		RazoringMargin: 300,
+
+		Futility:       true,
+		FutilityDepth:  2,
+		FutilityMargin: 150,
+
+		CheckExtension:       true,
+		SingleReplyExtension: true,
+
+		MateDistance: true,
+	}
+}
+
+// lmrTable is how many plies to reduce by, indexed by depth and move number
+type lmrTable [maxDepth + 1][lmrMaxMoves]int
+
+func newLMRTable(params Params) *lmrTable {
+	table := &lmrTable{}
+	for depth := 1; depth <= maxDepth; depth++ {
+		for move := 1; move < lmrMaxMoves; move++ {
+			r := params.LMRBase + math.Log(float64(depth))*math.Log(float64(move))/params.LMRDivisor
+			table[depth][move] = int(r)
+		}
+	}
+	return table
+}
+
+// SetParams changes the selective search, i.e. to turn a technique off.
+// The zero Params turns every technique off.
+func (s *SearchInfo) SetParams(params Params) {
+	s.params = params
+	s.lmr = nil
+	if params.LMR {
+		s.lmr = newLMRTable(params)
+	}
+}
+
+/*
+lateMoveReduction is how many plies shallower to search a move. Moves are
+ordered best first, so once the first few are searched the rest very rarely
+turn out best. Searching them shallower saves most of their cost, and any
+that still beat alpha are searched again at full depth.
+
+Only quiet moves are reduced, and never when in check or for killers,
+since those are the moves most likely to matter. The caller decides that.
+*/
+func (s *SearchInfo) lateMoveReduction(depth, moveNum int, pvNode bool) int {
+	if !s.params.LMR || depth < s.params.LMRMinDepth || moveNum <= s.params.LMRMinMoves {
+		return 0
+	}
+	if moveNum >= lmrMaxMoves {
+		moveNum = lmrMaxMoves - 1
+	}
+
+	r := s.lmr[depth][moveNum]
+	if pvNode {
+		r--
+	}
+
+	// always leave at least a ply to search
+	if r > depth-2 {
+		r = depth - 2
+	}
+	if r < 0 {
+		r = 0
+	}
+	return r
+}
+
+/*
+reverseFutilityPrune is null move pruning without the search. Close to the
+leaves, a position with a static eval far enough above beta is very unlikely
+to drop below it in the few plies left, so it can fail high straight away.
+*/
+func (s *SearchInfo) reverseFutilityPrune(staticEval, beta eval.Score, depth int) bool {
+	return s.params.ReverseFutility &&
+		depth <= s.params.ReverseFutilityDepth &&
+		!beta.IsMate() &&
+		staticEval-eval.Score(s.params.ReverseFutilityMargin*depth) >= beta
+}
+
+/*
+razoringAllowed is the opposite, for a static eval so far below alpha that
+only winning material could bring it back. That's what the quiescence search
+looks at, so it's used instead and the node fails low if it agrees.
+*/
+func (s *SearchInfo) razoringAllowed(staticEval, alpha eval.Score, depth int) bool {
+	return s.params.Razoring &&
+		depth <= s.params.RazoringDepth &&
+		!alpha.IsMate() &&
+		staticEval+eval.Score(s.params.RazoringMargin*depth) <= alpha
+}
+
+/*
+futilityPrune decides if quiet moves can be skipped at a frontier node, one
+or two plies from the quiescence search. If the static eval plus a margin
+can't reach alpha, a quiet move won't either. Captures, promotions and
+checks are still searched, since they can change the eval by much more.
+*/
+func (s *SearchInfo) futilityPrune(staticEval, alpha eval.Score, depth int) bool {
+	return s.params.Futility &&
+		depth <= s.params.FutilityDepth &&
+		!alpha.IsMate() &&
+		staticEval+eval.Score(s.params.FutilityMargin*depth) <= alpha
+}
+
+/*
+extension is how many plies deeper to search a node. A line full of checks
+loses depth like any other, so a forced mate just past the horizon would be
+missed even though the checks leave the opponent with hardly any choice.
+Extending while in check lets those lines play out, and costs little since
+there are only a few ways out of a check.
+
+Without the check extension, only checks with a single legal reply are
+extended. Either way it's at most one ply per node, so a run of checks can't
+extend the search forever, it gains a ply back for every two it spends.
+*/
+func (s *SearchInfo) extension(p *position.Position, inCheck bool) int {
+	if !inCheck {
+		return 0
+	}
+	if s.params.CheckExtension {
+		return 1
+	}
+	if s.params.SingleReplyExtension && len(*p.GenerateLegalMoves()) == 1 {
+		return 1
+	}
+	return 0
+}
+
+/*
+mateDistancePrune checks the window against the mates possible from this
+ply. Nothing found here can beat mating on the next move, or do worse than
+being mated right now. Once a mate has been found closer to the root, a node
+too deep to find anything shorter can't change the result, so it returns
+the bound straight away without searching.
+
+The window itself isn't narrowed, otherwise the mating move would fail high
+against the narrowed beta and never make it into the principal variation.
+*/
+func (s *SearchInfo) mateDistancePrune(alpha, beta eval.Score) (eval.Score, bool) {
+	if !s.params.MateDistance || s.searchPly == 0 {
+		return 0, false
+	}
+	if eval.MatedIn(s.searchPly) >= beta {
+		return beta, true
+	}
+	if eval.MateIn(s.searchPly+1) <= alpha {
+		return alpha, true
+	}
+	return 0, false
+}
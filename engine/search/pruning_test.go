@@ -0,0 +1,245 @@
+package search
+
+import (
+	"cacti-chess/engine/eval"
+	"cacti-chess/engine/position"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// newUnpruned is a search without any of the selective search, so
+// the score doesn't depend on the window it's searched with
+func newUnpruned() *SearchInfo {
+	s := New()
+	s.SetParams(Params{})
+	return s
+}
+
+func TestSearchInfo_SetParams(t *testing.T) {
+	t.Run("it only builds the reduction table with LMR on", func(t *testing.T) {
+		s := New()
+		assert.Equal(t, DefaultParams(), s.params)
+		require.NotNil(t, s.lmr)
+
+		s.SetParams(Params{})
+		assert.Nil(t, s.lmr)
+		assert.Equal(t, 0, s.lateMoveReduction(20, 30, false))
+	})
+}
+
+func TestSearchInfo_lateMoveReduction(t *testing.T) {
+	s := New()
+
+	type testCase struct {
+		name    string
+		depth   int
+		moveNum int
+		pvNode  bool
+		want    int
+	}
+
+	for _, tc := range []testCase{
+		{"too shallow", s.params.LMRMinDepth - 1, 20, false, 0},
+		{"an early move", 10, s.params.LMRMinMoves, false, 0},
+		{"a late move", 10, 20, false, s.lmr[10][20]},
+		{"a pv node", 10, 20, true, s.lmr[10][20] - 1},
+		{"past the table", 10, 200, false, s.lmr[10][lmrMaxMoves-1]},
+		{"leaving a ply", 3, 60, false, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, s.lateMoveReduction(tc.depth, tc.moveNum, tc.pvNode))
+		})
+	}
+
+	t.Run("it reduces more the later and deeper the move", func(t *testing.T) {
+		for depth := 2; depth <= maxDepth; depth++ {
+			for move := 2; move < lmrMaxMoves; move++ {
+				assert.GreaterOrEqual(t, s.lmr[depth][move], s.lmr[depth][move-1])
+				assert.GreaterOrEqual(t, s.lmr[depth][move], s.lmr[depth-1][move])
+			}
+		}
+	})
+}
+
+func TestSearchInfo_pruning(t *testing.T) {
+	s := New()
+	params := s.params
+
+	t.Run("reverse futility", func(t *testing.T) {
+		margin := eval.Score(params.ReverseFutilityMargin * 2)
+		assert.True(t, s.reverseFutilityPrune(100+margin, 100, 2))
+		assert.False(t, s.reverseFutilityPrune(99+margin, 100, 2))
+		assert.False(t, s.reverseFutilityPrune(5000, 100, params.ReverseFutilityDepth+1))
+		assert.False(t, s.reverseFutilityPrune(eval.Mate, eval.MateIn(10), 1))
+	})
+
+	t.Run("razoring", func(t *testing.T) {
+		margin := eval.Score(params.RazoringMargin)
+		assert.True(t, s.razoringAllowed(100-margin, 100, 1))
+		assert.False(t, s.razoringAllowed(101-margin, 100, 1))
+		assert.False(t, s.razoringAllowed(-5000, 100, params.RazoringDepth+1))
+		assert.False(t, s.razoringAllowed(-eval.Mate, eval.MatedIn(10), 1))
+	})
+
+	t.Run("futility", func(t *testing.T) {
+		margin := eval.Score(params.FutilityMargin)
+		assert.True(t, s.futilityPrune(100-margin, 100, 1))
+		assert.False(t, s.futilityPrune(101-margin, 100, 1))
+		assert.False(t, s.futilityPrune(-5000, 100, params.FutilityDepth+1))
+		assert.False(t, s.futilityPrune(-eval.Mate, eval.MatedIn(10), 1))
+	})
+
+	t.Run("each switch turns its technique off", func(t *testing.T) {
+		off := New()
+		off.SetParams(Params{})
+		assert.False(t, off.reverseFutilityPrune(5000, 100, 1))
+		assert.False(t, off.razoringAllowed(-5000, 100, 1))
+		assert.False(t, off.futilityPrune(-5000, 100, 1))
+	})
+}
+
+func TestSearchInfo_extension(t *testing.T) {
+	type testCase struct {
+		name     string
+		fen      string
+		check    bool
+		single   bool
+		expected int
+	}
+
+	// the king on h1 only has Kh2 in the first, and Kg2/Kh2 in the second
+	single := "k7/8/8/8/8/8/6P1/r6K w - - 0 1"
+	double := "k7/8/8/8/8/8/8/r6K w - - 0 1"
+	quiet := "k7/8/8/8/8/8/6P1/7K w - - 0 1"
+
+	for _, tc := range []testCase{
+		{"check", double, true, false, 1},
+		{"check with a single reply", single, true, true, 1},
+		{"single reply only", single, false, true, 1},
+		{"single reply with two replies", double, false, true, 0},
+		{"no extensions", single, false, false, 0},
+		{"not in check", quiet, true, true, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := position.FromFen(tc.fen)
+			require.Nil(t, err)
+
+			s := New()
+			s.params.CheckExtension = tc.check
+			s.params.SingleReplyExtension = tc.single
+			assert.Equal(t, tc.expected, s.extension(p, p.IsKingAttacked()))
+		})
+	}
+
+	t.Run("it finds a mate past the horizon", func(t *testing.T) {
+		// a smothered mate, where the checks are quiet moves the
+		// quiescence search can't see
+		fen := "r6k/6pp/8/6N1/2Q5/8/P5PP/5RK1 w - - 0 1"
+
+		p, err := position.FromFen(fen)
+		require.Nil(t, err)
+		score, _ := New().SearchPosition(p, Options{Depth: 4})
+		assert.Equal(t, eval.MateIn(5), score)
+
+		p, err = position.FromFen(fen)
+		require.Nil(t, err)
+		score, _ = newUnpruned().SearchPosition(p, Options{Depth: 4})
+		assert.False(t, score.IsMate())
+	})
+}
+
+func TestSearchInfo_mateDistancePrune(t *testing.T) {
+	s := New()
+	s.searchPly = 3
+
+	t.Run("it keeps searching a normal window", func(t *testing.T) {
+		_, ok := s.mateDistancePrune(-100, 100)
+		assert.False(t, ok)
+	})
+
+	t.Run("it fails low when a shorter mate was found", func(t *testing.T) {
+		score, ok := s.mateDistancePrune(eval.MateIn(4), eval.Infinity)
+		assert.True(t, ok)
+		assert.Equal(t, eval.MateIn(4), score)
+
+		_, ok = s.mateDistancePrune(eval.MateIn(5), eval.Infinity)
+		assert.False(t, ok)
+	})
+
+	t.Run("it fails high when being mated sooner is already enough", func(t *testing.T) {
+		score, ok := s.mateDistancePrune(-eval.Infinity, eval.MatedIn(3))
+		assert.True(t, ok)
+		assert.Equal(t, eval.MatedIn(3), score)
+	})
+
+	t.Run("it's off at the root or when switched off", func(t *testing.T) {
+		root := New()
+		_, ok := root.mateDistancePrune(eval.MateIn(1), eval.Infinity)
+		assert.False(t, ok)
+
+		s.SetParams(Params{})
+		_, ok = s.mateDistancePrune(eval.MateIn(4), eval.Infinity)
+		assert.False(t, ok)
+	})
+}
+
+// paramVariants are the default params with each technique switched off in turn
+func paramVariants() []struct {
+	name   string
+	params Params
+} {
+	all := DefaultParams()
+	noLMR, noReverseFutility, noRazoring, noFutility := all, all, all, all
+	noLMR.LMR = false
+	noReverseFutility.ReverseFutility = false
+	noRazoring.Razoring = false
+	noFutility.Futility = false
+	noCheckExtension, noExtensions, noMateDistance := all, all, all
+	noCheckExtension.CheckExtension = false
+	noExtensions.CheckExtension = false
+	noExtensions.SingleReplyExtension = false
+	noMateDistance.MateDistance = false
+
+	return []struct {
+		name   string
+		params Params
+	}{
+		{"all", all},
+		{"none", Params{}},
+		{"no lmr", noLMR},
+		{"no reverse futility", noReverseFutility},
+		{"no razoring", noRazoring},
+		{"no futility", noFutility},
+		{"no check extension", noCheckExtension},
+		{"no extensions", noExtensions},
+		{"no mate distance", noMateDistance},
+	}
+}
+
+func TestSearchInfo_SearchPosition_params(t *testing.T) {
+	for _, variant := range paramVariants() {
+		params := variant.params
+		t.Run(variant.name+" finds the mate", func(t *testing.T) {
+			p, err := position.FromFen("2bqkbn1/2pppp2/np2N3/r3P1p1/p2N2B1/5Q2/PPPPKPP1/RNB2r2 w KQkq - 0 1")
+			require.Nil(t, err)
+
+			s := New()
+			s.SetParams(params)
+			score, line := s.SearchPosition(p, Options{Depth: 4})
+			assert.Equal(t, eval.MateIn(3), score)
+			require.NotEmpty(t, line)
+			assert.Equal(t, "f3f7", line[0].ShortString())
+		})
+	}
+}
+
+// BenchmarkParams compares the selective search with each technique
+// switched off, run with
+// go test -bench Params -run XXX ./engine/search
+func BenchmarkParams(b *testing.B) {
+	for _, variant := range paramVariants() {
+		params := variant.params
+		b.Run(variant.name, func(b *testing.B) { benchmarkSearch(b, params) })
+	}
+}
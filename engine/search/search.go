@@ -3,52 +3,100 @@ package search
 import (
 	"cacti-chess/engine/eval"
 	"cacti-chess/engine/position"
-	"fmt"
-	"math"
+	"sync/atomic"
 	"time"
 )
 
-const maxDepth = 128
-const mate = 29000 // score for mate
+const maxDepth = eval.MaxPly
 
-var posInf = math.Inf(1)
-var negInf = math.Inf(-1)
+// null move pruning, see nullMoveAllowed
+const nullMoveMinDepth = 3
+const nullMoveDeepDepth = 7 // reduce more from here on
+
+// aspiration windows, see aspirationSearch
+const aspirationMinDepth = 4
+const aspirationWindow = 25     // centipawns either side of the last score
+const aspirationMaxWindow = 400 // widening past this opens the window fully
+
+// deltaMargin is added to captures in the quiescence search before
+// deciding they can't possibly raise alpha, to allow for positional gains
+const deltaMargin = 200
 
 type SearchInfo struct {
-	start time.Time
-	stop  time.Time
-	depth int
-	nodes uint64 // the count of positions the engine visited
+	start    time.Time
+	stop     time.Time // hard deadline, the search is aborted past this
+	softStop time.Time // no new depth is started past this
+	depth    int       // the last depth that finished searching
+	selDepth int       // the deepest ply reached in the current depth
+	nodes    uint64    // the count of positions the engine visited
 
 	scorer        Scorer
 	searchPly     int
-	searchHistory [13][120]int
-	searchKillers [2][]int
-	pvTable       *PrincipalVariationTable
-
-	quit    bool // quit is set to true if forcefully exited
-	stopped bool // stopped is more graceful
-
-	fh  int
-	fhf int
+	searchHistory [13][120]int                      // indexed by piece and to square
+	searchKillers [2][maxDepth + 1]position.Movekey // indexed by searchPly
+	pv            PrincipalVariation
+	tt            *TranspositionTable // kept between searches, unlike everything else
+	listener      Listener
+	contempt      eval.Score // centipawns a draw is worth avoiding, see drawScore
+	rootSide      int        // the side to move when the search started
+	params        Params     // selective search, see SetParams
+	lmr           *lmrTable  // reductions built from params, read only while searching
+
+	// quit and ponderHit are set from other goroutines while the search is
+	// running, so they're only accessed atomically. The search notices
+	// them the next time it checks the clock.
+	quit      int32 // set by Stop to end the search early
+	ponderHit int32 // set by PonderHit to start the clock
+	stopped   bool  // the search has been cut short, by a deadline or quit
+	pondering bool  // searching without deadlines until ponderHit
+
+	fh  int // beta cutoffs
+	fhf int // beta cutoffs on the first move searched
+
+	// lazy smp, see startHelpers
+	threads     int           // threads searching at once, including this one
+	helpers     []*SearchInfo // the other threads, while searching
+	isHelper    bool
+	sharedNodes uint64 // nodes published for the main thread, accessed atomically
 
 	// time controls
-	depthset  int // max depth
-	timeset   int // max time
+	options   Options
+	depthset  int    // max depth
+	nodeLimit uint64 // stop once nodes reaches this, 0 for no limit
+	timeset   bool   // if there are deadlines
 	movestogo int
-	infinite  int
 }
 
 func New() *SearchInfo {
 	s := &SearchInfo{}
-	s.scorer = &eval.PositionEvaluator{}
+	s.scorer = eval.NewPositionEvaluator()
+	s.tt = NewTranspositionTable(DefaultHashSize)
+	s.threads = 1
+	s.SetParams(DefaultParams())
 	s.Clear()
 	return s
 }
 
+// SetHashSize resizes the transposition table to roughly sizeMB
+func (s *SearchInfo) SetHashSize(sizeMB int) {
+	s.tt.Resize(sizeMB)
+}
+
+// ClearHash empties the transposition table, i.e. for a new game
+func (s *SearchInfo) ClearHash() {
+	s.tt.Clear()
+}
+
+// SetScorer changes how positions are evaluated. The transposition
+// table is cleared, since its scores came from the old scorer.
+func (s *SearchInfo) SetScorer(scorer Scorer) {
+	s.scorer = scorer
+	s.tt.Clear()
+}
+
 type Scorer interface {
-	Evaluate(p *position.Position) float64
-	EvaluateAbsolute(p *position.Position) float64
+	Evaluate(p *position.Position) eval.Score
+	EvaluateAbsolute(p *position.Position) eval.Score
 }
 
 // Clear resets the search
@@ -56,20 +104,24 @@ func (s *SearchInfo) Clear() {
 	// reset the search info
 	s.start = time.Time{}
 	s.stop = time.Time{}
+	s.softStop = time.Time{}
 
 	s.searchPly = 0
 	s.searchHistory = [13][120]int{}
-	s.searchKillers = [2][]int{}
-	s.pvTable = &PrincipalVariationTable{}
+	s.searchKillers = [2][maxDepth + 1]position.Movekey{}
+	s.pv = PrincipalVariation{}
+	s.fh = 0
+	s.fhf = 0
 
-	s.quit = false
+	atomic.StoreInt32(&s.quit, 0)
+	atomic.StoreInt32(&s.ponderHit, 0)
 	s.stopped = false
+	s.pondering = false
 
 	// limit controls
 	s.depthset = 0
-	s.timeset = 0
+	s.timeset = false
 	s.movestogo = 0
-	s.infinite = 0
 	s.depth = 0
 	s.nodes = 0
 }
@@ -137,17 +189,38 @@ and negate them, so the next iteration shows
 alpha = 10, beta = -20
 */
 
-func (s *SearchInfo) AlphaBeta(p *position.Position, alpha, beta float64, depth int, doNull bool) float64 {
+func (s *SearchInfo) AlphaBeta(p *position.Position, alpha, beta eval.Score, depth int, doNull bool) eval.Score {
 
-	// base case it's a leaf node, we return the evaluation relative to the current player.
-	if depth == 0 {
-		s.nodes++
-		return s.scorer.EvaluateAbsolute(p)
+	// lines that give check are searched deeper, see extension
+	inCheck := p.IsKingAttacked()
+	depth += s.extension(p, inCheck)
+
+	// base case it's a leaf node, we return the evaluation relative to the current player
+	// once any captures have been played out
+	if depth <= 0 {
+		return s.Quiescence(p, alpha, beta)
+	}
+	s.nodes++
+	if s.nodes%checkNodes == 0 || (s.nodeLimit > 0 && s.nodes >= s.nodeLimit) {
+		s.checkTime()
+		if s.stopped {
+			return 0
+		}
 	}
+	if s.searchPly > s.selDepth {
+		s.selDepth = s.searchPly
+	}
+	s.pv.clear(s.searchPly)
 
-	// edge cases for repetition, or if we are too far down return 0 for a draw
-	if p.IsRepetition() || p.GetFiftyMove() >= 100 {
-		return 0
+	// edge cases for repetition, or if we are too far down return 0 for a draw.
+	// The root is always searched, even if it's a repeat, so there's a move to play.
+	if s.searchPly > 0 && (p.IsRepetition() || p.GetFiftyMove() >= 100) {
+		return s.drawScore(p)
+	}
+
+	// a shorter mate was already found, see mateDistancePrune
+	if score, ok := s.mateDistancePrune(alpha, beta); ok {
+		return score
 	}
 
 	// endgame scenario, few pieces so we're searching a lot of depth
@@ -155,13 +228,81 @@ func (s *SearchInfo) AlphaBeta(p *position.Position, alpha, beta float64, depth
 		return s.scorer.EvaluateAbsolute(p)
 	}
 
+	// use the stored result if we've already searched this position deep
+	// enough. The root is always searched so we have a move to play, and
+	// so are other pv nodes, since a cutoff there would cut the line short.
+	pvNode := beta-alpha > 1
+	posKey := p.GetPosKey()
+	hashMove := position.Movekey(0)
+	if entry, ok := s.tt.probe(posKey, s.searchPly); ok {
+		hashMove = entry.move
+		if s.searchPly > 0 && !pvNode && int(entry.depth) >= depth {
+			switch entry.bound {
+			case boundExact:
+				return entry.score
+			case boundLower:
+				if entry.score >= beta {
+					return beta
+				}
+			case boundUpper:
+				if entry.score <= alpha {
+					return alpha
+				}
+			}
+		}
+	}
+	// the static eval is only trusted when the side to move isn't in check,
+	// and only used to prune when an exact score isn't needed
+	staticEval := eval.Score(0)
+	canPrune := !inCheck && !pvNode && s.searchPly > 0
+	if canPrune {
+		staticEval = s.scorer.EvaluateAbsolute(p)
+
+		if s.reverseFutilityPrune(staticEval, beta, depth) {
+			return beta
+		}
+
+		if s.razoringAllowed(staticEval, alpha, depth) {
+			score := s.Quiescence(p, alpha, beta)
+			if s.stopped {
+				return 0
+			}
+			if score <= alpha {
+				return alpha
+			}
+		}
+	}
+	futile := canPrune && s.futilityPrune(staticEval, alpha, depth)
+
+	// null move pruning, see nullMoveAllowed
+	if doNull && s.nullMoveAllowed(p, beta, depth, inCheck) {
+		p.MakeNullMove()
+		s.searchPly++
+		score := -s.AlphaBeta(p, -beta, -beta+1, depth-1-nullMoveReduction(depth), false)
+		p.UndoNullMove()
+		s.searchPly--
+
+		if s.stopped {
+			return 0
+		}
+
+		// a mate found after passing isn't a real mate, so only use the cutoff
+		if score >= beta && !score.IsMate() {
+			return beta
+		}
+	}
+
 	movelist := p.GenerateAllMoves()
+	s.scoreMoves(p, movelist, hashMove)
 
 	legal := 0
 	oldAlpha := alpha
 	bestMove := position.Movekey(0)
 
-	for _, mv := range *movelist {
+	for i := range *movelist {
+		pickNextMove(movelist, i)
+		mv := (*movelist)[i]
+
 		// if it's not legal, auto undo
 		if !p.MakeMove(mv.Key) {
 			continue
@@ -169,19 +310,57 @@ func (s *SearchInfo) AlphaBeta(p *position.Position, alpha, beta float64, depth
 
 		legal++
 
+		// p has already moved on, so this is if the move gives check
+		givesCheck := p.IsKingAttacked()
+		quiet := isQuiet(mv.Key) && !givesCheck
+
+		// a quiet move won't make up the difference at a futile node,
+		// once there's at least one move searched to fall back on
+		if futile && quiet && legal > 1 {
+			p.UndoMove()
+			continue
+		}
+
+		reduction := 0
+		if !inCheck && quiet && !s.isKiller(mv.Key) {
+			reduction = s.lateMoveReduction(depth, legal, pvNode)
+		}
+
 		// We call alphaBeta again to find the best response from our opponent
 		// we negate the return value so it's relative to US.
 		// We flip the alpha/beta order and sign as well for the same reason
-		score := -s.AlphaBeta(p, -beta, -alpha, depth-1, true)
+		s.searchPly++
+		score := s.principalVariationSearch(p, alpha, beta, depth, reduction, legal == 1)
 		p.UndoMove()
+		s.searchPly--
+
+		// the score can't be trusted if the search was cut short
+		if s.stopped {
+			return 0
+		}
 
 		// evaluate if this is better than what we've seen
 		if score > alpha {
 			if score >= beta {
+				if legal == 1 {
+					s.fhf++
+				}
+				s.fh++
+
+				if isQuiet(mv.Key) {
+					s.storeKiller(mv.Key)
+					s.storeHistory(p, mv.Key, depth)
+				}
+				s.tt.store(posKey, mv.Key, beta, depth, boundLower, s.searchPly)
 				return beta
 			}
 			alpha = score
 			bestMove = mv.Key
+			s.pv.update(s.searchPly, mv.Key)
+
+			if isQuiet(mv.Key) {
+				s.storeHistory(p, mv.Key, depth)
+			}
 		}
 	}
 
@@ -189,37 +368,335 @@ func (s *SearchInfo) AlphaBeta(p *position.Position, alpha, beta float64, depth
 	if legal == 0 {
 		// if we're mated, return the low mate score with the depth to mate
 		// added. i.e. mate in 2 is -28998, 3 -28997
-		if p.IsKingAttacked() {
-			return float64(-mate + s.searchPly)
+		if inCheck {
+			return eval.MatedIn(s.searchPly)
 		} else {
 			// stalemate
-			return 0
+			return s.drawScore(p)
 		}
 	}
 
 	if alpha != oldAlpha {
-		s.pvTable.Set(p, bestMove)
+		s.tt.store(posKey, bestMove, alpha, depth, boundExact, s.searchPly)
+	} else {
+		s.tt.store(posKey, position.Movekey(0), alpha, depth, boundUpper, s.searchPly)
 	}
 
 	return alpha
 }
 
+/*
+principalVariationSearch searches a move that was just made. With good move
+ordering the first move is usually the best, so it's searched with the full
+window. Every move after it only has to prove it's no better than alpha,
+which a null window (alpha, alpha+1) search can do much quicker since nearly
+everything cuts off. If one does turn out better, the null window only says
+so without an exact score, so it's searched again with the full window.
+
+Late moves may also be reduced, see lateMoveReduction. A reduced move that
+beats alpha is searched again at full depth before it's trusted.
+*/
+func (s *SearchInfo) principalVariationSearch(p *position.Position, alpha, beta eval.Score, depth, reduction int, first bool) eval.Score {
+	if first {
+		return -s.AlphaBeta(p, -beta, -alpha, depth-1, true)
+	}
+
+	score := -s.AlphaBeta(p, -alpha-1, -alpha, depth-1-reduction, true)
+	if reduction > 0 && score > alpha && !s.stopped {
+		score = -s.AlphaBeta(p, -alpha-1, -alpha, depth-1, true)
+	}
+	if score > alpha && score < beta && !s.stopped {
+		score = -s.AlphaBeta(p, -beta, -alpha, depth-1, true)
+	}
+	return score
+}
+
+/*
+Quiescence keeps searching captures and promotions past the end of AlphaBeta
+until the position is quiet. Otherwise a leaf node could be evaluated in the
+middle of an exchange, i.e. right after QxP without seeing PxQ. This is known
+as the horizon effect.
+
+Since not every move is searched, the side to move can "stand pat" and take the
+static evaluation if no capture improves on it. This doesn't hold when in check,
+where every evasion is searched instead.
+
+Delta pruning skips captures that can't raise alpha even if the captured piece
+was free, along with a margin for any positional gain.
+*/
+func (s *SearchInfo) Quiescence(p *position.Position, alpha, beta eval.Score) eval.Score {
+	s.pv.clear(s.searchPly)
+	s.nodes++
+	if s.nodes%checkNodes == 0 || (s.nodeLimit > 0 && s.nodes >= s.nodeLimit) {
+		s.checkTime()
+		if s.stopped {
+			return 0
+		}
+	}
+	if s.searchPly > s.selDepth {
+		s.selDepth = s.searchPly
+	}
+
+	if p.IsRepetition() || p.GetFiftyMove() >= 100 {
+		return s.drawScore(p)
+	}
+
+	if s.searchPly > maxDepth {
+		return s.scorer.EvaluateAbsolute(p)
+	}
+
+	inCheck := p.IsKingAttacked()
+	standPat := s.scorer.EvaluateAbsolute(p)
+
+	var movelist *position.Movelist
+	if inCheck {
+		movelist = p.GenerateAllMoves()
+	} else {
+		if standPat >= beta {
+			return beta
+		}
+
+		// even winning a queen won't help
+		if standPat+eval.Score(position.PwQ.Value())+deltaMargin < alpha {
+			return alpha
+		}
+
+		if standPat > alpha {
+			alpha = standPat
+		}
+		movelist = p.GenerateAllCaptures()
+	}
+
+	legal := 0
+	for i := range *movelist {
+		// captures are already scored by MVV-LVA
+		pickNextMove(movelist, i)
+		mv := (*movelist)[i]
+
+		if !inCheck && mv.Key.GetPromoted() == position.EMPTY {
+			gain := eval.Score(mv.Key.GetCaptured().Value())
+			if mv.Key.GetCaptured() == position.EMPTY {
+				gain = eval.Score(position.PwP.Value()) // en passant
+			}
+			if standPat+gain+deltaMargin < alpha {
+				continue
+			}
+		}
+
+		if !p.MakeMove(mv.Key) {
+			continue
+		}
+		legal++
+
+		s.searchPly++
+		score := -s.Quiescence(p, -beta, -alpha)
+		p.UndoMove()
+		s.searchPly--
+
+		if s.stopped {
+			return 0
+		}
+
+		if score > alpha {
+			if score >= beta {
+				return beta
+			}
+			alpha = score
+		}
+	}
+
+	// no way out of check
+	if inCheck && legal == 0 {
+		return eval.MatedIn(s.searchPly)
+	}
+
+	return alpha
+}
+
+/*
+nullMoveAllowed decides if null move pruning can be tried. The idea is that
+passing is almost always worse than the best move. If the opponent moves
+twice in a row and we're still above beta, a real search would very likely
+fail high too, so it can be cut off after only a shallow search.
+
+That doesn't hold when
+  - in check, since passing is illegal
+  - the side to move only has pawns left. Those endgames are full of
+    zugzwang, where any move is worse than passing.
+  - the last move was already a null move, since two in a row just
+    searches the same position with less depth
+  - beta is infinite, as nothing could ever fail high
+*/
+func (s *SearchInfo) nullMoveAllowed(p *position.Position, beta eval.Score, depth int, inCheck bool) bool {
+	return !inCheck &&
+		s.searchPly > 0 &&
+		depth >= nullMoveMinDepth &&
+		beta != eval.Infinity &&
+		p.GetBigPieceCount()[p.GetSide()] > 1 // the king counts as a big piece
+}
+
+// nullMoveReduction is how much shallower the null move is searched, on top
+// of the ply for the move itself. Deeper searches can afford to reduce more.
+func nullMoveReduction(depth int) int {
+	if depth >= nullMoveDeepDepth {
+		return 3
+	}
+	return 2
+}
+
+// SetContempt sets how many centipawns the engine thinks it's ahead of its
+// opponent by. A positive contempt avoids draws, negative goes looking for them.
+func (s *SearchInfo) SetContempt(cp int) {
+	s.contempt = eval.Score(cp)
+}
+
+// drawScore scores a draw from the side to move. Contempt is for the side
+// that started the search, so the opponent sees the opposite.
+func (s *SearchInfo) drawScore(p *position.Position) eval.Score {
+	if p.GetSide() == s.rootSide {
+		return -s.contempt
+	}
+	return s.contempt
+}
+
+// moveOrdering is the fraction of beta cutoffs that happened on the
+// first move searched. The closer to 1, the better the move ordering.
+func (s *SearchInfo) moveOrdering() float64 {
+	if s.fh == 0 {
+		return 0
+	}
+	return float64(s.fhf) / float64(s.fh)
+}
+
+// Options limit how long a search runs. With no Depth, Nodes, Time or
+// MoveTime the search keeps going until maxDepth or Stop.
 type Options struct {
-	Depth int
+	Depth    int    // max depth to search
+	Nodes    uint64 // stop once this many nodes are searched, after the first depth
+	Infinite bool   // ignore the clock, only stop at Depth or Stop
+	Ponder   bool   // ignore the clock until PonderHit, i.e. thinking on the opponent's time
+
+	// the clock for the side to move
+	Time         time.Duration // time left
+	Increment    time.Duration // added after each move
+	MovesToGo    int           // moves until the next time control, 0 for sudden death
+	MoveTime     time.Duration // search exactly this long, ignoring the clock
+	MoveOverhead time.Duration // kept back from each move for lag
 }
 
-func (s *SearchInfo) SearchPosition(p *position.Position, options Options) (bestScore float64, bestLine []position.Movekey) {
+// SearchPosition runs an iterative deepening search, returning the score and
+// line from the deepest search that finished before any deadline was hit.
+// The line is only empty when there are no legal moves, and the score is then
+// mate or a draw. A root that repeats an earlier position is still searched.
+func (s *SearchInfo) SearchPosition(p *position.Position, options Options) (bestScore eval.Score, bestLine []position.Movekey) {
+	s.prepare(options)
+	s.rootSide = p.GetSide()
+	s.tt.NewSearch()
 
-	bestMove := position.Movekey(0)
+	wg := s.startHelpers(p)
+	bestScore, bestLine = s.iterativeDeepening(p, 1)
+	s.stopHelpers(wg)
 
-	s.pvTable = &PrincipalVariationTable{}
+	return bestScore, bestLine
+}
 
-	// iterative deepening
-	for i := 1; i <= options.Depth; i++ {
-		bestScore = s.AlphaBeta(p, negInf, posInf, i, true)
-		fmt.Printf("info depth: %v, side: %v, score: %v, move: %v, nodes: %v\n", i, p.GetSide(), bestScore, bestMove.ShortString(), s.nodes)
+// prepare resets everything that only lasts for a single search
+func (s *SearchInfo) prepare(options Options) {
+	s.start = time.Now()
+	s.options = options
+	s.timeset = false
+	s.pondering = options.Ponder
+	if !s.pondering {
+		s.startClock(s.start)
+	}
+	s.movestogo = options.MovesToGo
+	s.depthset = options.Depth
+	if s.depthset <= 0 || s.depthset > maxDepth {
+		s.depthset = maxDepth
+	}
+	s.nodeLimit = 0
+	if options.Nodes > 0 {
+		s.nodeLimit = s.nodes + options.Nodes
+	}
+	s.depth = 0
+	s.stopped = false
+
+	s.pv = PrincipalVariation{}
+	s.searchPly = 0
+	s.searchHistory = [13][120]int{}
+	s.searchKillers = [2][maxDepth + 1]position.Movekey{}
+	s.fh = 0
+	s.fhf = 0
+}
+
+// iterativeDeepening searches one depth at a time, starting from the given
+// depth, until a deadline or depthset. Each depth is quick compared to the
+// next, and fills the tables that order the moves of the next one.
+func (s *SearchInfo) iterativeDeepening(p *position.Position, from int) (bestScore eval.Score, bestLine []position.Movekey) {
+	for i := from; i <= s.depthset; i++ {
+		s.selDepth = 0
+		score := s.aspirationSearch(p, i, bestScore)
+		if s.stopped {
+			break
+		}
+
+		bestScore = score
+		bestLine = s.pv.Line()
+		s.depth = i
+		s.report(bestScore, bestLine)
+
+		// there are no legal moves, searching deeper won't find any
+		if len(bestLine) == 0 {
+			break
+		}
+
+		// a deeper search probably won't finish in time
+		s.checkTime()
+		if s.stopped || (s.timeset && time.Now().After(s.softStop)) {
+			break
+		}
 	}
 
-	bestLine = s.pvTable.GetBestLine(p)
 	return bestScore, bestLine
 }
+
+/*
+aspirationSearch searches the root with a narrow window around the score of
+the previous depth, since it usually only changes a little. The narrower the
+window, the more cutoffs. If the score does land outside the window, the
+search failed high/low and only knows the score is past that bound, so the
+window is widened on that side and searched again.
+
+Mate scores jump around too much between depths for a window to help.
+*/
+func (s *SearchInfo) aspirationSearch(p *position.Position, depth int, prevScore eval.Score) eval.Score {
+	if depth < aspirationMinDepth || prevScore.IsMate() {
+		return s.AlphaBeta(p, -eval.Infinity, eval.Infinity, depth, true)
+	}
+
+	delta := eval.Score(aspirationWindow)
+	alpha, beta := prevScore-delta, prevScore+delta
+
+	for {
+		score := s.AlphaBeta(p, alpha, beta, depth, true)
+		if s.stopped {
+			return score
+		}
+
+		delta *= 2
+		switch {
+		case score <= alpha:
+			alpha -= delta
+			if delta > aspirationMaxWindow {
+				alpha = -eval.Infinity
+			}
+		case score >= beta:
+			beta += delta
+			if delta > aspirationMaxWindow {
+				beta = eval.Infinity
+			}
+		default:
+			return score
+		}
+	}
+}
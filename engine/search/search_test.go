@@ -1,11 +1,12 @@
 package search
 
 import (
+	"cacti-chess/engine/eval"
 	"cacti-chess/engine/position"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	"math"
 	"testing"
+	"time"
 )
 
 func TestSearchInfo_SearchPosition(t *testing.T) {
@@ -14,14 +15,14 @@ func TestSearchInfo_SearchPosition(t *testing.T) {
 		require.Nil(t, err)
 
 		s := New()
-		val := s.AlphaBeta(p, math.Inf(-1), math.Inf(1), 1, false)
-		assert.Equal(t, float64(30), val)
-		line := s.pvTable.GetBestLine(p)
+		val := s.AlphaBeta(p, -eval.Infinity, eval.Infinity, 1, false)
+		assert.Equal(t, eval.Score(53), val)
+		line := s.pv.Line()
 		names := []string{}
 		for _, mv := range line {
 			names = append(names, mv.ShortString())
 		}
-		assert.Equal(t, []string{"d2d4"}, names)
+		assert.Equal(t, []string{"e2e4"}, names)
 	})
 
 	t.Run("checkmate test - 0", func(t *testing.T) {
@@ -29,9 +30,9 @@ func TestSearchInfo_SearchPosition(t *testing.T) {
 		require.Nil(t, err)
 
 		s := New()
-		val := s.AlphaBeta(p, math.Inf(-1), math.Inf(1), 2, false)
-		require.Equal(t, float64(29000), val)
-		line := s.pvTable.GetBestLine(p)
+		val := s.AlphaBeta(p, -eval.Infinity, eval.Infinity, 2, false)
+		require.Equal(t, eval.MateIn(1), val)
+		line := s.pv.Line()
 		names := []string{}
 		for _, mv := range line {
 			names = append(names, mv.ShortString())
@@ -46,7 +47,7 @@ func TestSearchInfo_SearchPosition(t *testing.T) {
 		s := New()
 
 		val, line := s.SearchPosition(p, Options{Depth: 3})
-		assert.Equal(t, float64(-29000), val)
+		assert.Equal(t, eval.MatedIn(2), val)
 
 		names := []string{}
 		for _, mv := range line {
@@ -61,7 +62,7 @@ func TestSearchInfo_SearchPosition(t *testing.T) {
 
 		s := New()
 		val, line := s.SearchPosition(p, Options{Depth: 6})
-		assert.Equal(t, float64(29000), val)
+		assert.Equal(t, eval.MateIn(5), val)
 		names := []string{}
 		for _, mv := range line {
 			names = append(names, mv.ShortString())
@@ -75,7 +76,7 @@ func TestSearchInfo_SearchPosition(t *testing.T) {
 
 		s := New()
 		val, line := s.SearchPosition(p, Options{Depth: 4})
-		assert.Equal(t, float64(29000), val)
+		assert.Equal(t, eval.MateIn(3), val)
 		names := []string{}
 		for _, mv := range line {
 			names = append(names, mv.ShortString())
@@ -106,9 +107,281 @@ func TestSearchInfo_SearchPosition(t *testing.T) {
 			p.MakeMove(mv)
 
 			s := New()
-			s.AlphaBeta(p, math.Inf(-1), math.Inf(1), 3, false)
-			line := s.pvTable.GetBestLine(p)
-			assert.Len(t, line, 3)
+			s.AlphaBeta(p, -eval.Infinity, eval.Infinity, 3, false)
+			// checks are extended, so the line can go past the depth
+			line := s.pv.Line()
+			assert.GreaterOrEqual(t, len(line), 3)
+		}
+	})
+
+	t.Run("a repeated root still has a line", func(t *testing.T) {
+		p, err := position.FromFen("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+		require.Nil(t, err)
+		for _, mv := range []string{"g1f3", "g8f6", "f3g1", "f6g8"} {
+			key, err := p.ParseMove(mv)
+			require.Nil(t, err)
+			require.True(t, p.MakeMove(key))
+		}
+		require.True(t, p.IsRepetition())
+
+		s := New()
+		_, line := s.SearchPosition(p, Options{Depth: 3})
+		require.NotEmpty(t, line)
+		assert.True(t, p.MoveExists(line[0]))
+		assert.Equal(t, 3, s.depth)
+	})
+
+	t.Run("it counts nodes that aren't leaves", func(t *testing.T) {
+		// black is mated, so there's nothing left for quiescence to search
+		p, err := position.FromFen("k7/1Q6/K7/8/8/8/8/8 b - - 0 1")
+		require.Nil(t, err)
+
+		s := New()
+		val := s.AlphaBeta(p, -eval.Infinity, eval.Infinity, 1, false)
+		assert.Equal(t, -eval.MateIn(0), val)
+		assert.Equal(t, uint64(1), s.nodes)
+	})
+}
+
+func TestSearchInfo_SearchPosition_reproducible(t *testing.T) {
+	t.Run("it searches the same tree every time", func(t *testing.T) {
+		for _, fen := range benchPositions {
+			// the same position is searched twice, so making and
+			// undoing moves mustn't change the order moves come in
+			p, err := position.FromFen(fen)
+			require.Nil(t, err)
+
+			first := New()
+			firstScore, firstLine := first.SearchPosition(p, Options{Depth: 5})
+			second := New()
+			secondScore, secondLine := second.SearchPosition(p, Options{Depth: 5})
+
+			assert.Equal(t, firstScore, secondScore, fen)
+			assert.Equal(t, firstLine, secondLine, fen)
+			assert.Equal(t, first.nodes, second.nodes, fen)
+		}
+	})
+}
+
+func TestSearchInfo_TranspositionTable(t *testing.T) {
+	t.Run("it searches fewer nodes with a warm table", func(t *testing.T) {
+		p, err := position.FromFen("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3")
+		require.Nil(t, err)
+
+		s := New()
+		coldScore, coldLine := s.SearchPosition(p, Options{Depth: 4})
+		coldNodes := s.nodes
+
+		s.Clear()
+		warmScore, warmLine := s.SearchPosition(p, Options{Depth: 4})
+		warmNodes := s.nodes
+
+		assert.Equal(t, coldScore, warmScore)
+		assert.Equal(t, coldLine[0], warmLine[0])
+		assert.Less(t, warmNodes, coldNodes)
+	})
+}
+
+func TestSearchInfo_TimeManagement(t *testing.T) {
+	t.Run("it stops at the deadline with the last completed depth", func(t *testing.T) {
+		p, err := position.FromFen("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3")
+		require.Nil(t, err)
+
+		s := New()
+		start := time.Now()
+		_, line := s.SearchPosition(p, Options{MoveTime: 200 * time.Millisecond})
+		elapsed := time.Since(start)
+
+		assert.Less(t, elapsed, time.Second)
+		assert.Greater(t, s.depth, 0)
+		assert.Less(t, s.depth, maxDepth)
+		require.NotEmpty(t, line)
+		assert.True(t, p.MoveExists(line[0]))
+	})
+
+	t.Run("it stops at the node limit with the last completed depth", func(t *testing.T) {
+		p, err := position.FromFen("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3")
+		require.Nil(t, err)
+
+		s := New()
+		s.nodes = 500 // the limit counts from wherever the search starts
+		_, line := s.SearchPosition(p, Options{Nodes: 5000})
+
+		assert.Equal(t, uint64(5500), s.nodes)
+		assert.Greater(t, s.depth, 0)
+		assert.Less(t, s.depth, maxDepth)
+		require.NotEmpty(t, line)
+		assert.True(t, p.MoveExists(line[0]))
+
+		// the limit is never overshot, whichever node reaches it
+		for _, limit := range []uint64{2500, 7000, 12345} {
+			s = New()
+			s.SearchPosition(p, Options{Nodes: limit})
+			assert.Equal(t, limit, s.nodes)
+		}
+
+		// a single depth always finishes, however few nodes it takes
+		s = New()
+		_, line = s.SearchPosition(p, Options{Nodes: 1})
+		assert.Equal(t, 1, s.depth)
+		require.NotEmpty(t, line)
+	})
+}
+
+func TestSearchInfo_Stop(t *testing.T) {
+	t.Run("it stops an infinite search from another goroutine", func(t *testing.T) {
+		p, err := position.FromFen("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3")
+		require.Nil(t, err)
+
+		s := New()
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			s.Stop()
+		}()
+
+		_, line := s.SearchPosition(p, Options{Infinite: true})
+		require.NotEmpty(t, line)
+		assert.True(t, p.MoveExists(line[0]))
+		assert.Less(t, s.depth, maxDepth)
+	})
+
+	t.Run("it starts the clock on ponderhit", func(t *testing.T) {
+		p, err := position.FromFen("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3")
+		require.Nil(t, err)
+
+		s := New()
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			s.PonderHit()
+		}()
+
+		start := time.Now()
+		_, line := s.SearchPosition(p, Options{Ponder: true, MoveTime: 100 * time.Millisecond})
+		require.NotEmpty(t, line)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
+
+func TestSearchInfo_SetContempt(t *testing.T) {
+	t.Run("it scores draws against the side that started the search", func(t *testing.T) {
+		white, err := position.FromFen("4k3/8/8/8/8/8/8/4K3 w - - 0 1")
+		require.Nil(t, err)
+		black, err := position.FromFen("4k3/8/8/8/8/8/8/4K3 b - - 0 1")
+		require.Nil(t, err)
+
+		s := New()
+		assert.Equal(t, eval.Score(0), s.drawScore(white))
+
+		s.SetContempt(20)
+		s.rootSide = position.WHITE
+		assert.Equal(t, eval.Score(-20), s.drawScore(white))
+		assert.Equal(t, eval.Score(20), s.drawScore(black))
+
+		s.rootSide = position.BLACK
+		assert.Equal(t, eval.Score(-20), s.drawScore(black))
+	})
+}
+
+func TestSearchInfo_nullMoveAllowed(t *testing.T) {
+	type testCase struct {
+		name    string
+		fen     string
+		ply     int
+		depth   int
+		beta    eval.Score
+		allowed bool
+	}
+
+	for _, tc := range []testCase{
+		{name: "middlegame", fen: "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3", ply: 2, depth: 4, beta: 50, allowed: true},
+		{name: "root", fen: "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3", ply: 0, depth: 4, beta: 50},
+		{name: "too shallow", fen: "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3", ply: 2, depth: 2, beta: 50},
+		{name: "infinite beta", fen: "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3", ply: 2, depth: 4, beta: eval.Infinity},
+		{name: "in check", fen: "rnbqk1nr/pppp1ppp/8/4p3/1b1PP3/8/PPP2PPP/RNBQKBNR w KQkq - 1 3", ply: 2, depth: 4, beta: 50},
+		{name: "only pawns", fen: "4k3/4p3/8/8/8/8/3P4/3NK3 b - - 0 1", ply: 2, depth: 4, beta: 50},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := position.FromFen(tc.fen)
+			require.Nil(t, err)
+
+			s := New()
+			s.searchPly = tc.ply
+			assert.Equal(t, tc.allowed, s.nullMoveAllowed(p, tc.beta, tc.depth, p.IsKingAttacked()))
+		})
+	}
+}
+
+func Test_nullMoveReduction(t *testing.T) {
+	assert.Equal(t, 2, nullMoveReduction(nullMoveMinDepth))
+	assert.Equal(t, 3, nullMoveReduction(nullMoveDeepDepth))
+}
+
+// benchPositions are searched by BenchmarkSearchPosition, run with
+// go test -bench SearchPosition -run XXX ./engine/search
+// to compare node counts and speed between search changes
+var benchPositions = []string{
+	"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+	"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3",
+	"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+	"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+	"r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10",
+}
+
+func BenchmarkSearchPosition(b *testing.B) {
+	benchmarkSearch(b, DefaultParams())
+}
+
+func benchmarkSearch(b *testing.B, params Params) {
+	nodes := uint64(0)
+	for i := 0; i < b.N; i++ {
+		for _, fen := range benchPositions {
+			p, err := position.FromFen(fen)
+			require.Nil(b, err)
+
+			s := New()
+			s.SetParams(params)
+			s.SearchPosition(p, Options{Depth: 6})
+			nodes += s.nodes
+		}
+	}
+	b.ReportMetric(float64(nodes)/float64(b.N), "nodes/op")
+}
+
+func TestSearchInfo_aspirationSearch(t *testing.T) {
+	t.Run("it widens the window until the score is inside it", func(t *testing.T) {
+		for _, fen := range benchPositions {
+			p, err := position.FromFen(fen)
+			require.Nil(t, err)
+
+			// pruning depends on the window, so the scores would only be close
+			full := newUnpruned().AlphaBeta(p, -eval.Infinity, eval.Infinity, aspirationMinDepth, true)
+			for _, guess := range []eval.Score{full, full - 300, full + 300, full + 5000} {
+				assert.Equal(t, full, newUnpruned().aspirationSearch(p, aspirationMinDepth, guess), fen)
+			}
+		}
+	})
+}
+
+func TestSearchInfo_principalVariationSearch(t *testing.T) {
+	t.Run("it re-searches moves that beat alpha", func(t *testing.T) {
+		for _, fen := range benchPositions {
+			p, err := position.FromFen(fen)
+			require.Nil(t, err)
+
+			s := New()
+			s.searchPly = 1
+			window := New()
+			window.searchPly = 1
+
+			for _, mv := range *p.GenerateLegalMoves() {
+				p.MakeMove(mv.Key)
+				full := -window.AlphaBeta(p, -eval.Infinity, eval.Infinity, 2, true)
+				scout := s.principalVariationSearch(p, full-1, eval.Infinity, 3, 0, false)
+				p.UndoMove()
+
+				// the scout fails high, so it's searched again for the exact score
+				assert.Equal(t, full, scout, fen)
+			}
 		}
 	})
 }
@@ -0,0 +1,83 @@
+package search
+
+import (
+	"cacti-chess/engine/position"
+	"sync"
+	"sync/atomic"
+)
+
+// MaxThreads is the most threads a single search will use
+const MaxThreads = 64
+
+// SetThreads sets how many threads search at once, including the main one
+func (s *SearchInfo) SetThreads(n int) {
+	if n < 1 {
+		n = 1
+	}
+	if n > MaxThreads {
+		n = MaxThreads
+	}
+	s.threads = n
+}
+
+/*
+startHelpers starts the other threads of a Lazy SMP search. Each helper runs
+its own iterative deepening on a copy of the position, with nothing shared
+except the transposition table. That sounds like wasted work, but every
+result one thread stores is a cutoff or a better ordered move for the others,
+so together they search deeper than the main thread would alone.
+
+Half of the helpers start a depth ahead, so they're not all searching the
+same tree at the same time. Only the main thread's result is ever used.
+*/
+func (s *SearchInfo) startHelpers(p *position.Position) *sync.WaitGroup {
+	wg := &sync.WaitGroup{}
+	s.helpers = nil
+
+	for i := 1; i < s.threads; i++ {
+		h := &SearchInfo{
+			scorer:   s.scorer,
+			tt:       s.tt,
+			threads:  1,
+			isHelper: true,
+			contempt: s.contempt,
+			rootSide: s.rootSide,
+			params:   s.params,
+			lmr:      s.lmr,
+		}
+		h.Clear()
+		h.prepare(Options{Depth: s.depthset, Infinite: true})
+		s.helpers = append(s.helpers, h)
+
+		wg.Add(1)
+		go func(h *SearchInfo, p *position.Position, from int) {
+			defer wg.Done()
+			h.iterativeDeepening(p, from)
+		}(h, p.Clone(), 1+i%2)
+	}
+
+	return wg
+}
+
+// stopHelpers stops every helper once the main thread is done, adding
+// their nodes to the main thread's count
+func (s *SearchInfo) stopHelpers(wg *sync.WaitGroup) {
+	for _, h := range s.helpers {
+		h.Stop()
+	}
+	wg.Wait()
+
+	for _, h := range s.helpers {
+		s.nodes += h.nodes
+	}
+	s.helpers = nil
+}
+
+// totalNodes adds up the nodes searched by every thread so far
+func (s *SearchInfo) totalNodes() uint64 {
+	nodes := s.nodes
+	for _, h := range s.helpers {
+		nodes += atomic.LoadUint64(&h.sharedNodes)
+	}
+	return nodes
+}
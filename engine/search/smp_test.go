@@ -0,0 +1,69 @@
+package search
+
+import (
+	"cacti-chess/engine/eval"
+	"cacti-chess/engine/position"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSearchInfo_SetThreads(t *testing.T) {
+	t.Run("it keeps the thread count in range", func(t *testing.T) {
+		s := New()
+		assert.Equal(t, 1, s.threads)
+
+		s.SetThreads(0)
+		assert.Equal(t, 1, s.threads)
+		s.SetThreads(4)
+		assert.Equal(t, 4, s.threads)
+		s.SetThreads(MaxThreads + 1)
+		assert.Equal(t, MaxThreads, s.threads)
+	})
+}
+
+func TestSearchInfo_LazySMP(t *testing.T) {
+	t.Run("it finds the same mate with helper threads", func(t *testing.T) {
+		p, err := position.FromFen("r5rk/5p1p/5R2/4B3/8/8/7P/7K w - - 0 1")
+		require.Nil(t, err)
+		before := p.ToFen()
+
+		s := New()
+		s.SetThreads(4)
+		score, line := s.SearchPosition(p, Options{Depth: 6})
+
+		assert.Equal(t, eval.MateIn(5), score)
+		require.NotEmpty(t, line)
+		assert.Equal(t, "f6a6", line[0].ShortString())
+		assert.Equal(t, before, p.ToFen())
+		assert.Nil(t, s.helpers)
+	})
+
+	t.Run("it plays a legal move against the clock", func(t *testing.T) {
+		p, err := position.FromFen("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3")
+		require.Nil(t, err)
+
+		s := New()
+		s.SetThreads(4)
+		_, line := s.SearchPosition(p, Options{MoveTime: 200 * time.Millisecond})
+
+		require.NotEmpty(t, line)
+		assert.True(t, p.MoveExists(line[0]))
+	})
+
+	t.Run("it adds up nodes from every thread", func(t *testing.T) {
+		s := New()
+		s.nodes = 10
+		s.helpers = []*SearchInfo{
+			{nodes: 7, sharedNodes: 5},
+			{nodes: 3, sharedNodes: 2},
+		}
+		assert.Equal(t, uint64(17), s.totalNodes())
+
+		s.stopHelpers(&sync.WaitGroup{})
+		assert.Equal(t, uint64(20), s.nodes)
+		assert.Nil(t, s.helpers)
+	})
+}
@@ -0,0 +1,125 @@
+package search
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// DefaultMoveOverhead is kept back from every move to allow for
+// lag between the engine and the GUI/server running the clock
+const DefaultMoveOverhead = 50 * time.Millisecond
+
+// defaultMovesToGo is how many more moves a sudden death game is
+// assumed to last, so the clock is spread out over them
+const defaultMovesToGo = 30
+
+// checkNodes is how often the clock is checked, in nodes. Looking
+// at the time on every node would slow down the search.
+const checkNodes = 2048
+
+// minThinkTime is the least time given to a move, so the
+// deadlines never end up zero or negative
+const minThinkTime = time.Millisecond
+
+/*
+deadlines works out how long to think for from the clock, relative to when
+the search started. Zero means there's no time limit.
+
+The soft deadline is checked between iterations of the iterative deepening.
+Past it, the next depth probably won't finish in time so we play the best
+move we have. The hard deadline is checked during the search and aborts it
+outright, it's only hit when a single depth takes much longer than expected.
+
+  - movetime searches for exactly that long
+  - otherwise the remaining time is split between the moves to go,
+    plus most of the increment, since we get that back after moving
+  - no single move uses more than 3/4 of the remaining time
+*/
+func (o Options) deadlines() (soft, hard time.Duration) {
+	if o.Infinite {
+		return 0, 0
+	}
+
+	if o.MoveTime > 0 {
+		t := o.MoveTime - o.MoveOverhead
+		if t < minThinkTime {
+			t = minThinkTime
+		}
+		return t, t
+	}
+
+	if o.Time <= 0 {
+		return 0, 0
+	}
+
+	available := o.Time - o.MoveOverhead
+	if available < minThinkTime {
+		available = minThinkTime
+	}
+
+	movesToGo := o.MovesToGo
+	if movesToGo <= 0 || movesToGo > defaultMovesToGo {
+		movesToGo = defaultMovesToGo
+	}
+
+	soft = available/time.Duration(movesToGo) + o.Increment*3/4
+	hard = soft * 4
+
+	maxTime := available * 3 / 4
+	if hard > maxTime {
+		hard = maxTime
+	}
+	if hard < minThinkTime {
+		hard = minThinkTime
+	}
+	if soft > hard {
+		soft = hard
+	}
+	if soft < minThinkTime {
+		soft = minThinkTime
+	}
+
+	return soft, hard
+}
+
+// startClock sets the deadlines, counting from the given time
+func (s *SearchInfo) startClock(from time.Time) {
+	soft, hard := s.options.deadlines()
+	s.timeset = hard > 0
+	s.softStop = from.Add(soft)
+	s.stop = from.Add(hard)
+}
+
+// checkTime stops the search once the hard deadline has passed, the node
+// limit is reached or Stop was called. At least one depth has to finish
+// first, otherwise there'd be no move to play. Helper threads don't need
+// a move, so they stop right away.
+func (s *SearchInfo) checkTime() {
+	atomic.StoreUint64(&s.sharedNodes, s.nodes)
+
+	if s.pondering && atomic.LoadInt32(&s.ponderHit) == 1 {
+		s.pondering = false
+		s.startClock(time.Now())
+	}
+
+	if s.depth == 0 && !s.isHelper {
+		return
+	}
+	if atomic.LoadInt32(&s.quit) == 1 || (s.timeset && time.Now().After(s.stop)) || (s.nodeLimit > 0 && s.totalNodes() >= s.nodeLimit) {
+		s.stopped = true
+	}
+}
+
+// Stop ends a running search as soon as possible, keeping the result of the
+// last depth that finished. It's safe to call from another goroutine, and
+// lasts until Clear.
+func (s *SearchInfo) Stop() {
+	atomic.StoreInt32(&s.quit, 1)
+}
+
+// PonderHit switches a ponder search over to the normal time control, once
+// the opponent plays the expected move. It's safe to call from another
+// goroutine.
+func (s *SearchInfo) PonderHit() {
+	atomic.StoreInt32(&s.ponderHit, 1)
+}
@@ -0,0 +1,42 @@
+package search
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestOptions_deadlines(t *testing.T) {
+	t.Run("it has no deadlines without a clock", func(t *testing.T) {
+		soft, hard := Options{Depth: 5}.deadlines()
+		assert.Equal(t, time.Duration(0), soft)
+		assert.Equal(t, time.Duration(0), hard)
+	})
+
+	t.Run("it searches exactly the movetime", func(t *testing.T) {
+		soft, hard := Options{MoveTime: time.Second, MoveOverhead: 50 * time.Millisecond}.deadlines()
+		assert.Equal(t, 950*time.Millisecond, soft)
+		assert.Equal(t, 950*time.Millisecond, hard)
+	})
+
+	t.Run("it splits the clock between the moves to go", func(t *testing.T) {
+		soft, hard := Options{Time: 20 * time.Second, MovesToGo: 10}.deadlines()
+		assert.Equal(t, 2*time.Second, soft)
+		assert.Equal(t, 8*time.Second, hard)
+	})
+
+	t.Run("it uses most of the increment", func(t *testing.T) {
+		soft, _ := Options{Time: 30 * time.Second, Increment: 2 * time.Second}.deadlines()
+		assert.Equal(t, time.Second+1500*time.Millisecond, soft)
+	})
+
+	t.Run("it never uses more than 3/4 of the clock", func(t *testing.T) {
+		soft, hard := Options{Time: 4 * time.Second, MovesToGo: 1}.deadlines()
+		assert.Equal(t, 3*time.Second, soft)
+		assert.Equal(t, 3*time.Second, hard)
+
+		soft, hard = Options{Time: 10 * time.Millisecond, MoveOverhead: 50 * time.Millisecond}.deadlines()
+		assert.Equal(t, minThinkTime, soft)
+		assert.Equal(t, minThinkTime, hard)
+	})
+}
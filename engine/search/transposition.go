@@ -0,0 +1,258 @@
+package search
+
+import (
+	"cacti-chess/engine/eval"
+	"cacti-chess/engine/position"
+	"math"
+	"sync/atomic"
+	"unsafe"
+)
+
+// DefaultHashSize is the transposition table size in MB when none is given
+const DefaultHashSize = 16
+
+// bucketSize is how many entries share a single index. A new entry
+// replaces the least valuable entry in its bucket.
+const bucketSize = 4
+
+// bound describes how a stored score relates to the true score of a position
+type bound uint8
+
+const (
+	boundNone  bound = iota
+	boundExact       // the score is exact, alpha < score < beta
+	boundUpper       // every move failed low, score <= alpha
+	boundLower       // a move failed high, score >= beta
+)
+
+// ttEntry is an unpacked entry, as returned by probe
+type ttEntry struct {
+	key   uint64
+	move  position.Movekey
+	score eval.Score
+	depth int8
+	bound bound
+	age   uint8 // the search this entry was stored in
+}
+
+// Entries are packed into a single uint64 so they can be read and written
+// atomically. Scores fit in scoreBits, including mates and the infinite bounds.
+const (
+	moveBits  = 25
+	scoreBits = 18
+	depthBits = 8
+	boundBits = 2
+	ageBits   = 8
+
+	scoreShift = moveBits
+	depthShift = scoreShift + scoreBits
+	boundShift = depthShift + depthBits
+	ageShift   = boundShift + boundBits
+
+	scoreOffset = 1 << (scoreBits - 1)
+)
+
+func mask(bits uint) uint64 {
+	return 1<<bits - 1
+}
+
+func (e ttEntry) pack() uint64 {
+	return uint64(e.move)&mask(moveBits) |
+		uint64(int64(e.score)+scoreOffset)&mask(scoreBits)<<scoreShift |
+		uint64(uint8(e.depth))<<depthShift |
+		uint64(e.bound)&mask(boundBits)<<boundShift |
+		uint64(e.age)<<ageShift
+}
+
+func unpack(key, data uint64) ttEntry {
+	return ttEntry{
+		key:   key,
+		move:  position.Movekey(data & mask(moveBits)),
+		score: eval.Score(int64(data>>scoreShift&mask(scoreBits)) - scoreOffset),
+		depth: int8(uint8(data >> depthShift)),
+		bound: bound(data >> boundShift & mask(boundBits)),
+		age:   uint8(data >> ageShift),
+	}
+}
+
+// ttSlot is an entry as it's stored in the table. Every search thread reads
+// and writes slots without locking, so two threads could write to the same
+// slot at once and leave the key from one with the data from the other. To
+// catch that, the key is stored XORed with the data. A torn slot won't match
+// its key when probed, and is treated like any other miss.
+type ttSlot struct {
+	check uint64 // key ^ data
+	data  uint64
+}
+
+func (slot *ttSlot) load() ttEntry {
+	data := atomic.LoadUint64(&slot.data)
+	check := atomic.LoadUint64(&slot.check)
+	return unpack(check^data, data)
+}
+
+func (slot *ttSlot) save(e ttEntry) {
+	data := e.pack()
+	atomic.StoreUint64(&slot.data, data)
+	atomic.StoreUint64(&slot.check, e.key^data)
+}
+
+/*
+TranspositionTable caches the results of AlphaBeta by posKey, so positions
+reached by different move orders only need to be searched once. It's shared
+by every search thread, see ttSlot.
+
+Mate scores are stored relative to the position instead of the root. A mate
+found 3 plies below a position is the same mate no matter how many plies the
+position itself is from the root, so the ply is removed on store and added
+back on probe.
+
+Entries are replaced by preferring, in order
+  - an empty slot or the same position
+  - entries from an older search, since they're likely irrelevant now
+  - the shallowest entry in the bucket
+*/
+type TranspositionTable struct {
+	slots   []ttSlot
+	buckets uint64
+	age     uint8
+}
+
+// NewTranspositionTable creates a table using roughly sizeMB of memory
+func NewTranspositionTable(sizeMB int) *TranspositionTable {
+	tt := &TranspositionTable{}
+	tt.Resize(sizeMB)
+	return tt
+}
+
+// Resize reallocates the table, which also clears it
+func (tt *TranspositionTable) Resize(sizeMB int) {
+	if sizeMB < 1 {
+		sizeMB = 1
+	}
+
+	slotSize := uint64(unsafe.Sizeof(ttSlot{}))
+	tt.buckets = uint64(sizeMB) * 1024 * 1024 / slotSize / bucketSize
+	tt.slots = make([]ttSlot, tt.buckets*bucketSize)
+	tt.age = 0
+}
+
+// Clear removes all entries, i.e. when starting a new game
+func (tt *TranspositionTable) Clear() {
+	for i := range tt.slots {
+		tt.slots[i] = ttSlot{}
+	}
+	tt.age = 0
+}
+
+// NewSearch ages the table, so entries from previous
+// searches are replaced first
+func (tt *TranspositionTable) NewSearch() {
+	tt.age++
+}
+
+func (tt *TranspositionTable) bucket(key uint64) []ttSlot {
+	start := (key % tt.buckets) * bucketSize
+	return tt.slots[start : start+bucketSize]
+}
+
+// probe looks up a position, returning the stored entry with
+// any mate score adjusted to be relative to the current ply
+func (tt *TranspositionTable) probe(key uint64, ply int) (ttEntry, bool) {
+	bucket := tt.bucket(key)
+	for i := range bucket {
+		e := bucket[i].load()
+		if e.key == key && e.bound != boundNone {
+			e.score = scoreFromTT(e.score, ply)
+			return e, true
+		}
+	}
+	return ttEntry{}, false
+}
+
+// store saves the result of searching a position to a given depth
+func (tt *TranspositionTable) store(key uint64, move position.Movekey, score eval.Score, depth int, b bound, ply int) {
+	bucket := tt.bucket(key)
+
+	replace := 0
+	replaceEntry := bucket[0].load()
+	for i := range bucket {
+		e := bucket[i].load()
+		if e.bound == boundNone || e.key == key {
+			replace = i
+			replaceEntry = e
+			break
+		}
+		if replaceValue(&e, tt.age) < replaceValue(&replaceEntry, tt.age) {
+			replace = i
+			replaceEntry = e
+		}
+	}
+
+	// keep the old best move if we don't have a new one
+	if move.IsNoMove() && replaceEntry.key == key {
+		move = replaceEntry.move
+	}
+
+	// deep searches can take the depth past what fits, which
+	// would wrap around and look shallower than any other entry
+	if depth > math.MaxInt8 {
+		depth = math.MaxInt8
+	}
+
+	bucket[replace].save(ttEntry{
+		key:   key,
+		move:  move,
+		score: scoreToTT(score, ply),
+		depth: int8(depth),
+		bound: b,
+		age:   tt.age,
+	})
+}
+
+// replaceValue ranks how useful an entry is to keep, lower is replaced first
+func replaceValue(e *ttEntry, age uint8) int {
+	value := int(e.depth)
+	if e.age != age {
+		value -= 256
+	}
+	return value
+}
+
+func scoreToTT(score eval.Score, ply int) eval.Score {
+	if score >= eval.MateIn(maxDepth) {
+		return score + eval.Score(ply)
+	}
+	if score <= eval.MatedIn(maxDepth) {
+		return score - eval.Score(ply)
+	}
+	return score
+}
+
+func scoreFromTT(score eval.Score, ply int) eval.Score {
+	if score >= eval.MateIn(maxDepth) {
+		return score - eval.Score(ply)
+	}
+	if score <= eval.MatedIn(maxDepth) {
+		return score + eval.Score(ply)
+	}
+	return score
+}
+
+// HashFull estimates how full the table is in permille, counting only
+// entries from the current search like the UCI hashfull info expects
+func (tt *TranspositionTable) HashFull() int {
+	sample := 1000
+	if sample > len(tt.slots) {
+		sample = len(tt.slots)
+	}
+
+	used := 0
+	for i := 0; i < sample; i++ {
+		e := tt.slots[i].load()
+		if e.bound != boundNone && e.age == tt.age {
+			used++
+		}
+	}
+	return used * 1000 / sample
+}
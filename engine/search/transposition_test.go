@@ -0,0 +1,145 @@
+package search
+
+import (
+	"cacti-chess/engine/eval"
+	"cacti-chess/engine/position"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math"
+	"testing"
+)
+
+func TestTranspositionTable(t *testing.T) {
+	t.Run("it can store and probe entries", func(t *testing.T) {
+		tt := NewTranspositionTable(1)
+
+		_, ok := tt.probe(1234, 0)
+		assert.False(t, ok)
+
+		tt.store(1234, position.Movekey(42), 55, 3, boundExact, 0)
+		entry, ok := tt.probe(1234, 0)
+		require.True(t, ok)
+		assert.Equal(t, position.Movekey(42), entry.move)
+		assert.Equal(t, eval.Score(55), entry.score)
+		assert.Equal(t, int8(3), entry.depth)
+		assert.Equal(t, boundExact, entry.bound)
+	})
+
+	t.Run("it adjusts mate scores by ply", func(t *testing.T) {
+		tt := NewTranspositionTable(1)
+
+		// mate found 5 plies from the root, while the position is at ply 2
+		tt.store(1, position.Movekey(0), eval.MateIn(5), 3, boundExact, 2)
+		tt.store(2, position.Movekey(0), eval.MatedIn(5), 3, boundExact, 2)
+
+		// reached again at ply 4, the mate is now 7 plies from the root
+		entry, ok := tt.probe(1, 4)
+		require.True(t, ok)
+		assert.Equal(t, eval.MateIn(7), entry.score)
+
+		entry, ok = tt.probe(2, 4)
+		require.True(t, ok)
+		assert.Equal(t, eval.MatedIn(7), entry.score)
+	})
+
+	t.Run("it keeps the best move when storing without one", func(t *testing.T) {
+		tt := NewTranspositionTable(1)
+		tt.store(1, position.Movekey(42), 10, 3, boundLower, 0)
+		tt.store(1, position.Movekey(0), 5, 4, boundUpper, 0)
+
+		entry, ok := tt.probe(1, 0)
+		require.True(t, ok)
+		assert.Equal(t, position.Movekey(42), entry.move)
+		assert.Equal(t, boundUpper, entry.bound)
+	})
+
+	t.Run("it replaces the shallowest and oldest entries", func(t *testing.T) {
+		tt := NewTranspositionTable(1)
+
+		// fill up a single bucket
+		keys := []uint64{}
+		for i := 0; i < bucketSize; i++ {
+			key := uint64(i) * tt.buckets
+			keys = append(keys, key)
+			tt.store(key, position.Movekey(0), 0, 10-i, boundExact, 0)
+		}
+
+		// the shallowest entry is replaced
+		newKey := uint64(bucketSize) * tt.buckets
+		tt.store(newKey, position.Movekey(0), 0, 5, boundExact, 0)
+		_, ok := tt.probe(keys[bucketSize-1], 0)
+		assert.False(t, ok)
+		_, ok = tt.probe(newKey, 0)
+		assert.True(t, ok)
+
+		// after a new search, old entries go first even if they're deeper
+		tt.NewSearch()
+		tt.store(keys[0], position.Movekey(0), 0, 1, boundExact, 0)
+		tt.store(uint64(bucketSize+1)*tt.buckets, position.Movekey(0), 0, 1, boundExact, 0)
+		_, ok = tt.probe(keys[0], 0)
+		assert.True(t, ok)
+		_, ok = tt.probe(newKey, 0)
+		assert.False(t, ok)
+	})
+
+	t.Run("it keeps depths past what fits as the deepest entries", func(t *testing.T) {
+		tt := NewTranspositionTable(1)
+
+		// fill up a single bucket, with the deepest entry past int8
+		keys := []uint64{}
+		for i := 0; i < bucketSize; i++ {
+			key := uint64(i) * tt.buckets
+			keys = append(keys, key)
+			tt.store(key, position.Movekey(0), 0, math.MaxInt8+11-i*40, boundExact, 0)
+		}
+
+		entry, ok := tt.probe(keys[0], 0)
+		require.True(t, ok)
+		assert.Equal(t, int8(math.MaxInt8), entry.depth)
+
+		// a shallow entry replaces the shallowest, not the deepest
+		tt.store(uint64(bucketSize)*tt.buckets, position.Movekey(0), 0, 1, boundExact, 0)
+		_, ok = tt.probe(keys[0], 0)
+		assert.True(t, ok)
+		_, ok = tt.probe(keys[bucketSize-1], 0)
+		assert.False(t, ok)
+	})
+
+	t.Run("it packs entries without losing anything", func(t *testing.T) {
+		for _, e := range []ttEntry{
+			{key: 99, move: position.Movekey(0x185C58f), score: -1234, depth: 12, bound: boundUpper, age: 255},
+			{key: 7, move: position.Movekey(1), score: eval.MateIn(3), depth: -1, bound: boundLower, age: 1},
+			{key: 8, score: eval.MatedIn(3), depth: 127, bound: boundExact},
+		} {
+			assert.Equal(t, e, unpack(e.key, e.pack()))
+		}
+	})
+
+	t.Run("it ignores slots torn by two threads writing at once", func(t *testing.T) {
+		tt := NewTranspositionTable(1)
+		tt.store(1, position.Movekey(42), 10, 3, boundExact, 0)
+		tt.store(1+tt.buckets, position.Movekey(43), 20, 4, boundExact, 0)
+
+		// the check from one write with the data from the other
+		bucket := tt.bucket(1)
+		bucket[0].data = bucket[1].data
+
+		_, ok := tt.probe(1, 0)
+		assert.False(t, ok)
+		entry, ok := tt.probe(1+tt.buckets, 0)
+		require.True(t, ok)
+		assert.Equal(t, eval.Score(20), entry.score)
+	})
+
+	t.Run("it can be cleared and resized", func(t *testing.T) {
+		tt := NewTranspositionTable(1)
+		tt.store(1, position.Movekey(42), 10, 3, boundExact, 0)
+		tt.Clear()
+		_, ok := tt.probe(1, 0)
+		assert.False(t, ok)
+
+		buckets := tt.buckets
+		tt.Resize(2)
+		assert.Equal(t, buckets*2, tt.buckets)
+	})
+}
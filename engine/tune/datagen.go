@@ -0,0 +1,222 @@
+package tune
+
+import (
+	"cacti-chess/engine/eval"
+	"cacti-chess/engine/position"
+	"cacti-chess/engine/search"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+)
+
+const startFen = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+// Games are adjudicated once one side has been winning by this much for
+// this many plies in a row, since it would only be a matter of time, and
+// drawn after maxGamePlies, in case neither side makes any progress.
+const (
+	adjudicateScore eval.Score = 1500
+	adjudicatePlies            = 8
+	maxGamePlies               = 500
+)
+
+// GenOptions control the self-play games played by Generate
+type GenOptions struct {
+	Games    int    // how many games to play
+	Threads  int    // games played at once, each with its own search
+	Depth    int    // how deep to search each move, if there's no node limit
+	Nodes    uint64 // how many nodes to search each move
+	HashSize int    // the transposition table of each thread, in MB
+
+	RandomPlies int           // random moves played to start each game
+	Seed        int64         // the random openings are the same for the same seed
+	Scorer      search.Scorer // nil for the built-in evaluation
+
+	SkipInCheck  bool // leave out positions where the side to move is in check
+	SkipTactical bool // leave out positions where the best move is a capture or promotion
+}
+
+/*
+Generate plays games of the engine against itself, for positions labelled
+with their search score and the result of their game. Each game starts from
+a few random moves, so no two games are alike, then every move is searched
+to a fixed depth or node count.
+
+Positions with a mate score are always left out, and there are filters for
+positions in check or where the best move wins material, since they aren't
+quiet. The static evaluation can't see what's about to be taken, so those
+positions say more about the search than the evaluation.
+
+The positions of each game are passed to write once it's over, from one
+thread at a time. Generation stops at the first error from write. Each game
+is seeded by its number, so with a node or depth limit the same games are
+played however many threads play them.
+*/
+func Generate(options GenOptions, write func(game []Entry) error) error {
+	if options.Depth <= 0 && options.Nodes == 0 {
+		return fmt.Errorf("a depth or node limit is needed")
+	}
+	if options.Threads < 1 {
+		options.Threads = runtime.NumCPU()
+	}
+	if options.Nodes > 0 {
+		// let the node limit alone decide how deep each search goes
+		options.Depth = 0
+	}
+
+	games := make(chan int)
+	mu := sync.Mutex{}
+	var writeErr error
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < options.Threads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			s := search.New()
+			if options.HashSize > 0 {
+				s.SetHashSize(options.HashSize)
+			}
+			if options.Scorer != nil {
+				s.SetScorer(options.Scorer)
+			}
+
+			for game := range games {
+				entries := playGame(s, rand.New(rand.NewSource(options.Seed+int64(game))), options)
+
+				mu.Lock()
+				if writeErr == nil {
+					writeErr = write(entries)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for game := 0; game < options.Games; game++ {
+		mu.Lock()
+		failed := writeErr != nil
+		mu.Unlock()
+		if failed {
+			break
+		}
+		games <- game
+	}
+	close(games)
+	wg.Wait()
+
+	return writeErr
+}
+
+// playGame plays a game from a random opening, returning the
+// positions that pass the filters labelled with the result
+func playGame(s *search.SearchInfo, rng *rand.Rand, options GenOptions) []Entry {
+	p := randomOpening(rng, options.RandomPlies)
+	s.ClearHash()
+
+	entries := []Entry{}
+	result, over := gameResult(p)
+	winning := 0 // plies in a row past adjudicateScore, positive for white
+	for ply := 0; !over; ply++ {
+		if ply == maxGamePlies {
+			result = 0.5
+			break
+		}
+
+		s.Clear()
+		score, line := s.SearchPosition(p, search.Options{Depth: options.Depth, Nodes: options.Nodes})
+		if len(line) == 0 {
+			// gameResult already found every game with no legal moves
+			// over, so this shouldn't happen, but call it a draw if it does
+			result = 0.5
+			break
+		}
+		move := line[0]
+
+		skip := score.IsMate() ||
+			(options.SkipInCheck && p.IsKingAttacked()) ||
+			(options.SkipTactical && (move.IsCapture() || move.GetPromoted() != position.EMPTY))
+		if !skip {
+			entries = append(entries, Entry{Position: p.Clone(), Score: score})
+		}
+
+		whiteScore := score
+		if p.GetSide() == position.BLACK {
+			whiteScore = -score
+		}
+		switch {
+		case whiteScore >= adjudicateScore && winning >= 0:
+			winning++
+		case whiteScore <= -adjudicateScore && winning <= 0:
+			winning--
+		default:
+			winning = 0
+		}
+		if winning >= adjudicatePlies || winning <= -adjudicatePlies {
+			result = 0.0
+			if winning > 0 {
+				result = 1.0
+			}
+			break
+		}
+
+		p.MakeMove(move)
+		result, over = gameResult(p)
+	}
+
+	for i := range entries {
+		entries[i].Result = result
+	}
+	return entries
+}
+
+// randomOpening plays random moves from the starting position,
+// starting again if it runs into the end of the game
+func randomOpening(rng *rand.Rand, plies int) *position.Position {
+	for {
+		p, _ := position.FromFen(startFen)
+		for i := 0; i < plies; i++ {
+			moves := *p.GenerateLegalMoves()
+			if len(moves) == 0 {
+				break
+			}
+			p.MakeMove(moves[rng.Intn(len(moves))].Key)
+		}
+
+		if _, over := gameResult(p); !over {
+			return p
+		}
+	}
+}
+
+// gameResult is the result of a game if it's over, from white's side
+func gameResult(p *position.Position) (result float64, over bool) {
+	if len(*p.GenerateLegalMoves()) == 0 {
+		if !p.IsKingAttacked() {
+			return 0.5, true
+		}
+		if p.GetSide() == position.WHITE {
+			return 0, true
+		}
+		return 1, true
+	}
+
+	if p.GetFiftyMove() >= 100 || p.IsRepetition() || insufficientMaterial(p) {
+		return 0.5, true
+	}
+	return 0, false
+}
+
+// insufficientMaterial is if neither side can ever mate,
+// with nothing left but a single knight or bishop each
+func insufficientMaterial(p *position.Position) bool {
+	count := p.GetPieceCount()
+	for _, pce := range []position.Piece{position.PwP, position.PwR, position.PwQ, position.PbP, position.PbR, position.PbQ} {
+		if count[pce] > 0 {
+			return false
+		}
+	}
+	return count[position.PwN]+count[position.PwB] <= 1 && count[position.PbN]+count[position.PbB] <= 1
+}
@@ -0,0 +1,123 @@
+package tune
+
+import (
+	"cacti-chess/engine/position"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	options := GenOptions{
+		Games:        2,
+		Threads:      1,
+		Nodes:        300,
+		HashSize:     1,
+		RandomPlies:  8,
+		Seed:         1,
+		SkipInCheck:  true,
+		SkipTactical: true,
+	}
+
+	// generate returns each game as an epd, sorted so
+	// the order they finished in doesn't matter
+	generate := func(options GenOptions) []string {
+		games := []string{}
+		err := Generate(options, func(game []Entry) error {
+			b := &strings.Builder{}
+			require.Nil(t, WriteEPD(b, game))
+			games = append(games, b.String())
+
+			for _, e := range game {
+				assert.False(t, e.Position.IsKingAttacked(), e.Position.ToFen())
+				assert.False(t, e.Score.IsMate())
+				assert.Equal(t, game[0].Result, e.Result)
+			}
+			return nil
+		})
+		require.Nil(t, err)
+		sort.Strings(games)
+		return games
+	}
+
+	t.Run("it plays the same games on any number of threads", func(t *testing.T) {
+		games := generate(options)
+		require.Len(t, games, 2)
+		assert.NotEmpty(t, strings.Join(games, ""))
+
+		options.Threads = 2
+		assert.Equal(t, games, generate(options))
+
+		options.Seed = 2
+		assert.NotEqual(t, games, generate(options))
+	})
+
+	t.Run("it stops at the first write error", func(t *testing.T) {
+		written := 0
+		err := Generate(options, func(game []Entry) error {
+			written++
+			return errors.New("disk full")
+		})
+		assert.EqualError(t, err, "disk full")
+		assert.Equal(t, 1, written)
+	})
+
+	t.Run("it needs a limit for each move", func(t *testing.T) {
+		err := Generate(GenOptions{Games: 1}, func(game []Entry) error { return nil })
+		assert.NotNil(t, err)
+	})
+}
+
+func Test_randomOpening(t *testing.T) {
+	p := randomOpening(rand.New(rand.NewSource(1)), 8)
+	assert.Equal(t, 8, p.GetHisPly())
+
+	_, over := gameResult(p)
+	assert.False(t, over)
+}
+
+func Test_gameResult(t *testing.T) {
+	type testCase struct {
+		name   string
+		fen    string
+		result float64
+		over   bool
+	}
+	testCases := []testCase{
+		{name: "in progress", fen: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", result: 0, over: false},
+		{name: "white mated", fen: "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3", result: 0, over: true},
+		{name: "black mated", fen: "R5k1/5ppp/8/8/8/8/8/6K1 b - - 0 1", result: 1, over: true},
+		{name: "stalemate", fen: "7k/5Q2/6K1/8/8/8/8/8 b - - 0 1", result: 0.5, over: true},
+		{name: "fifty moves", fen: "4k3/8/8/8/8/8/4P3/4K3 w - - 100 80", result: 0.5, over: true},
+		{name: "bishop against knight", fen: "4k3/8/3n4/8/8/8/4B3/4K3 w - - 0 1", result: 0.5, over: true},
+		{name: "two knights can still mate", fen: "4k3/8/8/8/8/8/3NN3/4K3 w - - 0 1", result: 0, over: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := position.FromFen(tc.fen)
+			require.Nil(t, err)
+
+			result, over := gameResult(p)
+			assert.Equal(t, tc.over, over)
+			assert.Equal(t, tc.result, result)
+		})
+	}
+
+	t.Run("repetition", func(t *testing.T) {
+		p, err := position.FromFen("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+		require.Nil(t, err)
+		for _, mv := range []string{"g1f3", "g8f6", "f3g1", "f6g8"} {
+			key, err := p.ParseMove(mv)
+			require.Nil(t, err)
+			require.True(t, p.MakeMove(key))
+		}
+
+		result, over := gameResult(p)
+		assert.True(t, over)
+		assert.Equal(t, 0.5, result)
+	})
+}
@@ -0,0 +1,118 @@
+package tune
+
+import (
+	"bufio"
+	"cacti-chess/engine/eval"
+	"cacti-chess/engine/position"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Entry is a position labelled with the result of the game it came from
+type Entry struct {
+	Position *position.Position
+	Result   float64    // 1 for a white win, 0.5 for a draw, 0 for a black win
+	Score    eval.Score // the search score for the side to move, 0 if there isn't one
+}
+
+// results are the game results as written in the c9 opcode
+var results = map[string]float64{
+	"1-0":     1,
+	"1/2-1/2": 0.5,
+	"0-1":     0,
+}
+
+/*
+ReadEPD reads positions labelled with game results, one per line, like
+
+	rnbqkb1r/pppppppp/5n2/8/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - c9 "1/2-1/2";
+
+where the first four fields are the fen without the move counters, and
+the result is given by the c9 opcode. A search score can be given by the
+ce opcode, in centipawns for the side to move. Other opcodes are ignored,
+as are empty lines and lines starting with #.
+*/
+func ReadEPD(r io.Reader) ([]Entry, error) {
+	entries := []Entry{}
+
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		entry, err := parseEPDLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNum, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+func parseEPDLine(line string) (Entry, error) {
+	fields := strings.SplitN(line, " ", 5)
+	if len(fields) < 5 {
+		return Entry{}, fmt.Errorf("expected a position and opcodes, found %q", line)
+	}
+
+	p, err := position.FromFen(strings.Join(fields[:4], " ") + " 0 1")
+	if err != nil {
+		return Entry{}, err
+	}
+
+	entry := Entry{Position: p}
+	found := false
+	for _, op := range strings.Split(fields[4], ";") {
+		op = strings.TrimSpace(op)
+		switch {
+		case strings.HasPrefix(op, "c9 "):
+			value := strings.Trim(strings.TrimSpace(op[3:]), `"`)
+			result, ok := results[value]
+			if !ok {
+				return Entry{}, fmt.Errorf("unknown result %q", value)
+			}
+			entry.Result = result
+			found = true
+		case strings.HasPrefix(op, "ce "):
+			score, err := strconv.Atoi(strings.TrimSpace(op[3:]))
+			if err != nil {
+				return Entry{}, fmt.Errorf("bad score %q", op[3:])
+			}
+			entry.Score = eval.Score(score)
+		}
+	}
+
+	if !found {
+		return Entry{}, fmt.Errorf("no c9 result in %q", line)
+	}
+	return entry, nil
+}
+
+// WriteEPD writes positions in the format read by ReadEPD, with
+// their search score and the result of their game
+func WriteEPD(w io.Writer, entries []Entry) error {
+	for _, e := range entries {
+		fen := strings.Split(e.Position.ToFen(), " ")
+		_, err := fmt.Fprintf(w, "%s ce %d; c9 \"%s\";\n", strings.Join(fen[:4], " "), e.Score, resultString(e.Result))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resultString is a result as it's written in the c9 opcode
+func resultString(result float64) string {
+	switch {
+	case result > 0.5:
+		return "1-0"
+	case result < 0.5:
+		return "0-1"
+	}
+	return "1/2-1/2"
+}
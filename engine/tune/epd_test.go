@@ -0,0 +1,63 @@
+package tune
+
+import (
+	"cacti-chess/engine/eval"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestReadEPD(t *testing.T) {
+	t.Run("it reads labelled positions", func(t *testing.T) {
+		epd := `# a comment
+rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 c9 "1-0";
+
+4k3/8/8/8/8/8/4P3/4K3 w - - ce 150; c9 "1/2-1/2";
+4k3/8/8/8/8/8/4p3/4K3 b - - c9 "0-1"; id "last";
+`
+		entries, err := ReadEPD(strings.NewReader(epd))
+		require.Nil(t, err)
+		require.Len(t, entries, 3)
+
+		assert.Equal(t, 1.0, entries[0].Result)
+		assert.Equal(t, "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", entries[0].Position.ToFen())
+		assert.Equal(t, eval.Score(0), entries[0].Score)
+		assert.Equal(t, 0.5, entries[1].Result)
+		assert.Equal(t, eval.Score(150), entries[1].Score)
+		assert.Equal(t, 0.0, entries[2].Result)
+	})
+
+	t.Run("it rejects lines without a result", func(t *testing.T) {
+		_, err := ReadEPD(strings.NewReader("4k3/8/8/8/8/8/4P3/4K3 w - - ce 150;\n"))
+		assert.EqualError(t, err, `line 1: no c9 result in "4k3/8/8/8/8/8/4P3/4K3 w - - ce 150;"`)
+	})
+
+	t.Run("it rejects unknown results", func(t *testing.T) {
+		_, err := ReadEPD(strings.NewReader(`4k3/8/8/8/8/8/4P3/4K3 w - - c9 "*";`))
+		assert.NotNil(t, err)
+	})
+
+	t.Run("it rejects bad positions", func(t *testing.T) {
+		_, err := ReadEPD(strings.NewReader(`4k3/8/8/8/8/8/4P3/4K3 x - - c9 "1-0";`))
+		assert.NotNil(t, err)
+	})
+
+	t.Run("it rejects bad scores", func(t *testing.T) {
+		_, err := ReadEPD(strings.NewReader(`4k3/8/8/8/8/8/4P3/4K3 w - - ce +1.5; c9 "1-0";`))
+		assert.EqualError(t, err, `line 1: bad score "+1.5"`)
+	})
+}
+
+func TestWriteEPD(t *testing.T) {
+	epd := `rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 ce -35; c9 "1-0";
+4k3/8/8/8/8/8/4P3/4K3 w - - ce 150; c9 "1/2-1/2";
+4k3/8/8/8/8/8/4p3/4K3 b - - ce 0; c9 "0-1";
+`
+	entries, err := ReadEPD(strings.NewReader(epd))
+	require.Nil(t, err)
+
+	b := &strings.Builder{}
+	require.Nil(t, WriteEPD(b, entries))
+	assert.Equal(t, epd, b.String())
+}
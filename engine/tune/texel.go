@@ -0,0 +1,166 @@
+package tune
+
+import (
+	"cacti-chess/engine/eval"
+	"math"
+	"reflect"
+	"runtime"
+	"sync"
+)
+
+/*
+Tuner fits evaluation weights to game results with Texel's method. Each
+position is scored with the static evaluation, which a sigmoid turns into
+the expected result of the game, from 0 for a black win to 1 for a white
+win. The error is the mean squared difference from the actual results, and
+the weights are changed one at a time for as long as the error goes down.
+
+The positions should be quiet, since the static evaluation can't see a
+piece that's about to be taken.
+*/
+type Tuner struct {
+	entries []Entry
+	threads int
+	k       float64 // scales scores to the sigmoid, see FitK
+}
+
+// NewTuner creates a tuner over labelled positions, using a number
+// of threads to evaluate them
+func NewTuner(entries []Entry, threads int) *Tuner {
+	if threads < 1 {
+		threads = runtime.NumCPU()
+	}
+	return &Tuner{entries: entries, threads: threads, k: 1}
+}
+
+// sigmoid is the expected result of a game, from a score from white's side
+func sigmoid(k float64, score eval.Score) float64 {
+	return 1 / (1 + math.Pow(10, -k*float64(score)/400))
+}
+
+// Error is the mean squared error of the expected results, using weights
+func (t *Tuner) Error(params *eval.Params) float64 {
+	return t.error(params, t.k)
+}
+
+func (t *Tuner) error(params *eval.Params, k float64) float64 {
+	if len(t.entries) == 0 {
+		return 0
+	}
+
+	// no pawn table, since the weights change between calls
+	scr := eval.PositionEvaluator{}
+	scr.SetParams(params)
+
+	sums := make([]float64, t.threads)
+	chunk := (len(t.entries) + t.threads - 1) / t.threads
+	wg := sync.WaitGroup{}
+	for i := 0; i < t.threads; i++ {
+		start, end := i*chunk, (i+1)*chunk
+		if start >= len(t.entries) {
+			break
+		}
+		if end > len(t.entries) {
+			end = len(t.entries)
+		}
+
+		wg.Add(1)
+		go func(i int, entries []Entry) {
+			defer wg.Done()
+			for _, e := range entries {
+				diff := e.Result - sigmoid(k, scr.Evaluate(e.Position))
+				sums[i] += diff * diff
+			}
+		}(i, t.entries[start:end])
+	}
+	wg.Wait()
+
+	sum := 0.0
+	for _, s := range sums {
+		sum += s
+	}
+	return sum / float64(len(t.entries))
+}
+
+// FitK finds the sigmoid scale that best fits the weights as they are,
+// so tuning changes the weights themselves rather than their scale
+func (t *Tuner) FitK(params *eval.Params) float64 {
+	best, bestErr := t.k, t.error(params, t.k)
+
+	// narrow down a digit at a time
+	lo, hi := 0.0, 3.0
+	for step := 0.1; step >= 0.0001; step /= 10 {
+		for k := lo; k <= hi; k += step {
+			if err := t.error(params, k); err < bestErr {
+				best, bestErr = k, err
+			}
+		}
+		lo, hi = math.Max(best-step, 0), best+step
+	}
+
+	t.k = best
+	return best
+}
+
+// Tune changes the weights in place to lower the error, for up to a
+// number of passes over every weight. It stops early once a pass
+// doesn't help, and reports the error after each pass.
+func (t *Tuner) Tune(params *eval.Params, passes int, progress func(pass int, err float64)) float64 {
+	weights := Weights(params)
+	bestErr := t.Error(params)
+
+	for pass := 1; pass <= passes; pass++ {
+		improved := false
+		for _, w := range weights {
+			*w++
+			if err := t.Error(params); err < bestErr {
+				bestErr, improved = err, true
+				continue
+			}
+
+			*w -= 2
+			if err := t.Error(params); err < bestErr {
+				bestErr, improved = err, true
+				continue
+			}
+
+			*w++
+		}
+
+		if progress != nil {
+			progress(pass, bestErr)
+		}
+		if !improved {
+			break
+		}
+	}
+
+	return bestErr
+}
+
+// Weights are the tunable weights in params, i.e. every int
+// not in a field tagged with tune:"-"
+func Weights(params *eval.Params) []*int {
+	weights := []*int{}
+
+	v := reflect.ValueOf(params).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).Tag.Get("tune") == "-" {
+			continue
+		}
+		weights = appendWeights(weights, v.Field(i))
+	}
+	return weights
+}
+
+func appendWeights(weights []*int, v reflect.Value) []*int {
+	switch v.Kind() {
+	case reflect.Int:
+		return append(weights, v.Addr().Interface().(*int))
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			weights = appendWeights(weights, v.Index(i))
+		}
+	}
+	return weights
+}
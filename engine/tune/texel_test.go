@@ -0,0 +1,66 @@
+package tune
+
+import (
+	"cacti-chess/engine/eval"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+// trainingEPD has an extra pawn win every time
+const trainingEPD = `4k3/8/8/8/8/8/PP6/4K3 w - - c9 "1-0";
+4k3/8/8/8/8/8/P1P5/4K3 b - - c9 "1-0";
+4k3/pp6/8/8/8/8/8/4K3 w - - c9 "0-1";
+4k3/p1p5/8/8/8/8/8/4K3 b - - c9 "0-1";
+4k3/p7/8/8/8/8/P7/4K3 w - - c9 "1/2-1/2";
+4k3/7p/8/8/8/8/7P/4K3 b - - c9 "1/2-1/2";
+`
+
+func TestTuner(t *testing.T) {
+	entries, err := ReadEPD(strings.NewReader(trainingEPD))
+	require.Nil(t, err)
+
+	t.Run("it scores a perfect fit as no error", func(t *testing.T) {
+		tuner := NewTuner(entries[4:], 2)
+		params := eval.DefaultParams()
+		assert.InDelta(t, 0, tuner.Error(&params), 1e-9)
+	})
+
+	t.Run("it fits k to the weights", func(t *testing.T) {
+		tuner := NewTuner(entries, 2)
+		params := eval.DefaultParams()
+
+		before := tuner.Error(&params)
+		k := tuner.FitK(&params)
+		assert.Greater(t, k, 0.0)
+		assert.LessOrEqual(t, tuner.Error(&params), before)
+	})
+
+	t.Run("it lowers the error", func(t *testing.T) {
+		tuner := NewTuner(entries, 1)
+		params := eval.DefaultParams()
+		params.MaterialEg[0] = 10 // pawns are clearly worth more than this
+
+		before := tuner.Error(&params)
+		passes := 0
+		after := tuner.Tune(&params, 3, func(pass int, err float64) {
+			passes = pass
+		})
+		assert.Less(t, after, before)
+		assert.Equal(t, after, tuner.Error(&params))
+		assert.Equal(t, 3, passes)
+		assert.Greater(t, params.MaterialEg[0], 10)
+	})
+}
+
+func TestWeights(t *testing.T) {
+	params := eval.DefaultParams()
+	weights := Weights(&params)
+
+	// everything but the mobility base
+	assert.Len(t, weights, 5+5+2*6*64+4*8+6+2*8+2+4+8+2*4)
+
+	*weights[0] = 42
+	assert.Equal(t, 42, params.MaterialMg[0])
+}
@@ -5,6 +5,6 @@ go 1.16
 require (
 	github.com/BurntSushi/toml v0.3.1
 	github.com/dolegi/uci v0.0.0-20200615095626-a4c7c119bc41
-	github.com/stretchr/testify v1.7.0 // indirect
-	github.com/urfave/cli/v2 v2.3.0 // indirect
+	github.com/stretchr/testify v1.7.0
+	github.com/urfave/cli/v2 v2.3.0
 )
@@ -38,6 +38,7 @@ type Config struct {
 	}
 	Game struct {
 		Aborttime int
+		Pgn       string // file finished games are appended to
 	}
 }
 
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"cacti-chess/engine/pgn"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// gameOver checks the lichess game status for a finished game
+func gameOver(status string) bool {
+	return status != "" && status != "created" && status != "started"
+}
+
+// gameResult maps a finished lichess game to a pgn result. Only draws and
+// stalemates are drawn, other endings without a winner are left unknown.
+func gameResult(status, winner string) string {
+	switch {
+	case winner == "white":
+		return pgn.ResultWhiteWins
+	case winner == "black":
+		return pgn.ResultBlackWins
+	case status == "draw" || status == "stalemate":
+		return pgn.ResultDraw
+	default:
+		return pgn.ResultUnknown
+	}
+}
+
+// saveGame appends a finished game to the configured pgn file
+func saveGame(gameId string, full gameState, moves, status, winner string) {
+	// aborted games and games that never started have no moves worth keeping
+	if conf.Game.Pgn == "" || status == "aborted" || status == "noStart" {
+		return
+	}
+
+	fen := full.InitialFen
+	if fen == "" || fen == "startpos" {
+		fen = pgn.StartingFen
+	}
+
+	game := pgn.New(fen)
+	game.SetTag("Event", "lichess "+full.Speed+" game")
+	game.SetTag("Site", "https://lichess.org/"+gameId)
+	game.SetTag("Date", time.Now().Format("2006.01.02"))
+	game.SetTag("White", full.White.Name)
+	game.SetTag("Black", full.Black.Name)
+	game.SetTag("Termination", status)
+
+	p, err := game.StartPosition()
+	if err != nil {
+		log.Println("Failed to save game", gameId, err)
+		return
+	}
+	for _, mv := range strings.Fields(moves) {
+		movekey, err := p.ParseMove(mv)
+		if err == nil {
+			err = game.AddMove(p, movekey)
+		}
+		if err != nil {
+			log.Println("Failed to save game", gameId, err)
+			return
+		}
+	}
+
+	game.SetResult(gameResult(status, winner))
+
+	file, err := os.OpenFile(conf.Game.Pgn, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Println("Failed to open pgn file", err)
+		return
+	}
+	defer file.Close()
+
+	if err := pgn.Write(file, game); err != nil {
+		log.Println("Failed to save game", gameId, err)
+	}
+}
@@ -21,30 +21,39 @@ type gameState struct {
 	Speed      string
 	InitialFen string
 	State      struct {
-		Type  string
-		Moves string
-		Wtime int
-		Btime int
-		Winc  int
-		Binc  int
+		Type   string
+		Moves  string
+		Wtime  int
+		Btime  int
+		Winc   int
+		Binc   int
+		Status string
+		Winner string
 	}
 	White struct {
-		Id string
+		Id   string
+		Name string
 	}
 	Black struct {
-		Id string
+		Id   string
+		Name string
 	}
-	Moves string
-	Wtime int
-	Btime int
-	Winc  int
-	Binc  int
+	Moves  string
+	Wtime  int
+	Btime  int
+	Winc   int
+	Binc   int
+	Status string
+	Winner string
 }
 
 func streamGame(gameId string, eng *uci.Engine) {
 	resp := request("GET", "bot/game/stream/"+gameId)
 	dec := json.NewDecoder(resp.Body)
 
+	// the gameFull event has the players + starting fen, needed to save the game
+	var full gameState
+
 	for dec.More() {
 		var gS gameState
 		err := dec.Decode(&gS)
@@ -54,6 +63,11 @@ func streamGame(gameId string, eng *uci.Engine) {
 		log.Printf("%+v\n", gS)
 
 		if gS.Type == "gameState" {
+			if gameOver(gS.Status) {
+				saveGame(gameId, full, gS.Moves, gS.Status, gS.Winner)
+				continue
+			}
+
 			eng.Position(gS.Moves)
 
 			if white {
@@ -88,6 +102,7 @@ func streamGame(gameId string, eng *uci.Engine) {
 		}
 
 		if gS.Type == "gameFull" {
+			full = gS
 			eng.NewGame(uci.NewGameOpts{Variant: gS.Variant, InitialFen: gS.InitialFen, Moves: gS.State.Moves})
 			chat(gameId, "player", eng.Meta.Name)
 			chat(gameId, "spectator", eng.Meta.Name)
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"cacti-chess/engine/eval"
+	"cacti-chess/engine/nnue"
+	"cacti-chess/engine/search"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// optionType is one of the option types a GUI knows how to show
+type optionType string
+
+const (
+	optionSpin   optionType = "spin"   // a number between min and max
+	optionCheck  optionType = "check"  // true or false
+	optionCombo  optionType = "combo"  // one of a few predefined strings
+	optionString optionType = "string" // any text, i.e. a file path
+	optionButton optionType = "button" // no value, setting it performs an action
+)
+
+// option is a setting declared in the uci reply, and changed with setoption
+type option struct {
+	name  string
+	typ   optionType
+	def   string   // default value, unused for buttons
+	min   int      // spin only
+	max   int      // spin only
+	vars  []string // combo only
+	apply func(c *UCIClient, value string)
+}
+
+// String formats the option as it's declared to the GUI, i.e.
+// option name Hash type spin default 16 min 1 max 1024
+func (o option) String() string {
+	b := strings.Builder{}
+	b.WriteString(fmt.Sprintf("option name %s type %s", o.name, o.typ))
+
+	switch o.typ {
+	case optionButton:
+		return b.String()
+	case optionString:
+		def := o.def
+		if def == "" {
+			def = "<empty>"
+		}
+		b.WriteString(" default " + def)
+	default:
+		b.WriteString(" default " + o.def)
+	}
+
+	switch o.typ {
+	case optionSpin:
+		b.WriteString(fmt.Sprintf(" min %d max %d", o.min, o.max))
+	case optionCombo:
+		for _, v := range o.vars {
+			b.WriteString(" var " + v)
+		}
+	}
+
+	return b.String()
+}
+
+// parse checks a setoption value against the option type, returning
+// it in a canonical form. Spin values are clamped between min and max.
+func (o option) parse(value string) (string, error) {
+	switch o.typ {
+	case optionSpin:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a number", value)
+		}
+		if n < o.min {
+			n = o.min
+		}
+		if n > o.max {
+			n = o.max
+		}
+		return strconv.Itoa(n), nil
+	case optionCheck:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("%q is not true or false", value)
+		}
+		return strconv.FormatBool(b), nil
+	case optionCombo:
+		for _, v := range o.vars {
+			if strings.EqualFold(v, value) {
+				return v, nil
+			}
+		}
+		return "", fmt.Errorf("%q is not one of %v", value, o.vars)
+	case optionString:
+		if value == "<empty>" {
+			return "", nil
+		}
+		return value, nil
+	}
+	return "", nil
+}
+
+// atoi is for values that were already checked by option.parse
+func atoi(value string) int {
+	n, _ := strconv.Atoi(value)
+	return n
+}
+
+// engineOptions are declared in this order in the uci reply
+var engineOptions = []option{
+	{
+		name: "Hash", typ: optionSpin, def: strconv.Itoa(search.DefaultHashSize), min: 1, max: 1024,
+		apply: func(c *UCIClient, value string) {
+			c.search.SetHashSize(atoi(value))
+		},
+	},
+	{
+		name: "Clear Hash", typ: optionButton,
+		apply: func(c *UCIClient, value string) {
+			c.search.ClearHash()
+		},
+	},
+	{
+		name: "Threads", typ: optionSpin, def: "1", min: 1, max: search.MaxThreads,
+		apply: func(c *UCIClient, value string) {
+			c.search.SetThreads(atoi(value))
+		},
+	},
+	{
+		name: "Ponder", typ: optionCheck, def: "false",
+		// only tells the GUI we can ponder, go ponder does the work
+		apply: func(c *UCIClient, value string) {},
+	},
+	{
+		name: "Contempt", typ: optionSpin, def: "0", min: -100, max: 100,
+		apply: func(c *UCIClient, value string) {
+			c.search.SetContempt(atoi(value))
+		},
+	},
+	{
+		name: "EvalParams", typ: optionString, def: "",
+		apply: func(c *UCIClient, value string) {
+			c.setEvalParams(value)
+		},
+	},
+	{
+		name: "EvalFile", typ: optionString, def: "",
+		apply: func(c *UCIClient, value string) {
+			c.setEvalFile(value)
+		},
+	},
+	{
+		name: "UseNNUE", typ: optionCheck, def: "false",
+		apply: func(c *UCIClient, value string) {
+			c.useNNUE = value == "true"
+			c.updateScorer()
+		},
+	},
+	{
+		name: "Move Overhead", typ: optionSpin, def: strconv.Itoa(int(search.DefaultMoveOverhead.Milliseconds())), min: 0, max: 5000,
+		apply: func(c *UCIClient, value string) {
+			c.moveOverhead = time.Duration(atoi(value)) * time.Millisecond
+		},
+	},
+}
+
+// setEvalParams loads evaluation weights from a file, as written by the
+// tune command. An empty path goes back to the built-in weights, and a
+// file that can't be read leaves the current weights as they are.
+func (c *UCIClient) setEvalParams(path string) {
+	scorer := eval.NewPositionEvaluator()
+	if path != "" {
+		params, err := eval.LoadParams(path)
+		if err != nil {
+			fmt.Fprintf(logFile, "error loading eval params %q: %v\n", path, err)
+			return
+		}
+		scorer.SetParams(&params)
+	}
+	c.evaluator = scorer
+	c.updateScorer()
+}
+
+// setEvalFile loads the network used when UseNNUE is on. An empty path
+// unloads it, and a file that can't be read leaves the current network
+// as it is.
+func (c *UCIClient) setEvalFile(path string) {
+	var network *nnue.Network
+	if path != "" {
+		var err error
+		network, err = nnue.LoadNetwork(path)
+		if err != nil {
+			fmt.Fprintf(logFile, "error loading eval file %q: %v\n", path, err)
+			return
+		}
+	}
+	c.network = network
+	c.updateScorer()
+}
+
+// nnueActive is if the search is using the network
+func (c *UCIClient) nnueActive() bool {
+	return c.useNNUE && c.network != nil
+}
+
+// updateScorer gives the search the network if UseNNUE is on and one is
+// loaded, falling back to the classical evaluation otherwise
+func (c *UCIClient) updateScorer() {
+	if c.nnueActive() {
+		c.search.SetScorer(nnue.NewEvaluator(c.network))
+		return
+	}
+	if c.useNNUE {
+		fmt.Fprintln(logFile, "UseNNUE is on without an EvalFile, using the classical evaluation")
+	}
+	c.search.SetScorer(c.evaluator)
+}
+
+// findOption looks up an option by name. Names aren't case sensitive.
+func findOption(name string) (option, bool) {
+	for _, o := range engineOptions {
+		if strings.EqualFold(o.name, name) {
+			return o, true
+		}
+	}
+	return option{}, false
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"cacti-chess/engine/eval"
+	"cacti-chess/engine/nnue"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_option_String(t *testing.T) {
+	t.Run("it declares each type of option", func(t *testing.T) {
+		assert.Equal(t, "option name Hash type spin default 16 min 1 max 1024",
+			option{name: "Hash", typ: optionSpin, def: "16", min: 1, max: 1024}.String())
+		assert.Equal(t, "option name Ponder type check default false",
+			option{name: "Ponder", typ: optionCheck, def: "false"}.String())
+		assert.Equal(t, "option name Style type combo default Normal var Solid var Normal var Risky",
+			option{name: "Style", typ: optionCombo, def: "Normal", vars: []string{"Solid", "Normal", "Risky"}}.String())
+		assert.Equal(t, "option name EvalFile type string default <empty>",
+			option{name: "EvalFile", typ: optionString}.String())
+		assert.Equal(t, "option name Clear Hash type button",
+			option{name: "Clear Hash", typ: optionButton}.String())
+	})
+}
+
+func Test_option_parse(t *testing.T) {
+	type testCase struct {
+		name    string
+		option  option
+		value   string
+		want    string
+		wantErr bool
+	}
+
+	spin := option{typ: optionSpin, min: 1, max: 100}
+	combo := option{typ: optionCombo, vars: []string{"Solid", "Risky"}}
+
+	for _, tc := range []testCase{
+		{name: "spin", option: spin, value: "50", want: "50"},
+		{name: "spin below min", option: spin, value: "-5", want: "1"},
+		{name: "spin above max", option: spin, value: "500", want: "100"},
+		{name: "spin not a number", option: spin, value: "lots", wantErr: true},
+		{name: "check", option: option{typ: optionCheck}, value: "true", want: "true"},
+		{name: "check not a bool", option: option{typ: optionCheck}, value: "yes please", wantErr: true},
+		{name: "combo ignores case", option: combo, value: "risky", want: "Risky"},
+		{name: "combo not a var", option: combo, value: "Normal", wantErr: true},
+		{name: "string", option: option{typ: optionString}, value: "nets/eval.nnue", want: "nets/eval.nnue"},
+		{name: "string empty", option: option{typ: optionString}, value: "<empty>", want: ""},
+		{name: "button", option: option{typ: optionButton}, value: "", want: ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.option.parse(tc.value)
+			if tc.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			require.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestUCIClient_parseSetOption(t *testing.T) {
+	t.Run("it declares every option in the uci reply", func(t *testing.T) {
+		c := newUCIClient()
+		out := captureStdout(t, func() {
+			c.parseLine("uci")
+		})
+
+		for _, o := range engineOptions {
+			assert.Contains(t, out, o.String()+"\n")
+		}
+		assert.True(t, strings.HasSuffix(out, "uciok\n"))
+	})
+
+	t.Run("it applies options sent by the lichess bot", func(t *testing.T) {
+		c := newUCIClient()
+		c.parseLine("setoption name Contempt value 10")
+		c.parseLine("setoption name Threads value 2")
+		c.parseLine("setoption name Hash value 8")
+		c.parseLine("setoption name move overhead value 250")
+		assert.Equal(t, 250*time.Millisecond, c.moveOverhead)
+
+		c.parseLine("setoption name Move Overhead value soon")
+		assert.Equal(t, 250*time.Millisecond, c.moveOverhead)
+
+		c.parseLine("setoption name Clear Hash")
+		c.parseLine("setoption name Not An Option value 1")
+	})
+	t.Run("it loads eval params", func(t *testing.T) {
+		params := eval.DefaultParams()
+		params.MaterialEg[0] = 5000
+		path := filepath.Join(t.TempDir(), "params.toml")
+		file, err := os.Create(path)
+		require.Nil(t, err)
+		require.Nil(t, params.Write(file))
+		require.Nil(t, file.Close())
+
+		c := newUCIClient()
+		search := func() string {
+			return captureStdout(t, func() {
+				c.parseLine("position fen 4k3/8/8/8/8/8/4P3/4K3 w - - 0 1")
+				c.parseLine("go depth 1")
+				<-c.active.done
+			})
+		}
+
+		c.parseLine("setoption name EvalParams value " + path)
+		assert.Regexp(t, `score cp 5\d\d\d `, search())
+
+		c.parseLine("setoption name EvalParams value " + path + ".missing")
+		assert.Regexp(t, `score cp 5\d\d\d `, search())
+
+		c.parseLine("setoption name EvalParams value <empty>")
+		assert.NotRegexp(t, `score cp 5\d\d\d `, search())
+	})
+
+	t.Run("it switches to an nnue eval file", func(t *testing.T) {
+		// a network with every weight 0 scores everything as a draw
+		path := filepath.Join(t.TempDir(), "eval.nnue")
+		file, err := os.Create(path)
+		require.Nil(t, err)
+		require.Nil(t, nnue.NewNetwork(4).Write(file))
+		require.Nil(t, file.Close())
+
+		c := newUCIClient()
+		search := func() string {
+			return captureStdout(t, func() {
+				c.parseLine("position fen 4k3/8/8/8/8/8/4P3/4K3 w - - 0 1")
+				c.parseLine("go depth 1")
+				<-c.active.done
+			})
+		}
+		score := regexp.MustCompile(`score cp -?\d+ `)
+		classical := score.FindString(search())
+		assert.NotEqual(t, "score cp 0 ", classical)
+
+		// the network isn't used until UseNNUE is on
+		c.parseLine("setoption name EvalFile value " + path)
+		assert.Equal(t, classical, score.FindString(search()))
+
+		c.parseLine("setoption name UseNNUE value true")
+		assert.Contains(t, search(), "score cp 0 ")
+		assert.Contains(t, captureStdout(t, func() { c.parseLine("eval") }), "NNUE score: 0 (white's side)")
+
+		// a file that can't be loaded keeps the network
+		c.parseLine("setoption name EvalFile value " + path + ".missing")
+		assert.Contains(t, search(), "score cp 0 ")
+
+		// and the classical evaluation is used without one
+		c.parseLine("setoption name EvalFile value <empty>")
+		assert.NotContains(t, search(), "score cp 0 ")
+
+		c.parseLine("setoption name EvalFile value " + path)
+		c.parseLine("setoption name UseNNUE value false")
+		assert.NotContains(t, search(), "score cp 0 ")
+		assert.NotContains(t, captureStdout(t, func() { c.parseLine("eval") }), "NNUE score")
+	})
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"bufio"
+	"cacti-chess/engine/eval"
+	"cacti-chess/engine/nnue"
 	"cacti-chess/engine/position"
 	"cacti-chess/engine/search"
 	"fmt"
@@ -9,11 +11,15 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 // https://www.shredderchess.com/chess-features/uci-universal-chess-interface.html
 
+// defaultDepth is searched when go isn't given a depth or any time limits
+const defaultDepth = 5
+
 var logFile *os.File
 
 func init() {
@@ -26,9 +32,7 @@ func init() {
 func main() {
 	fmt.Fprintln(logFile, "starting")
 	reader := bufio.NewReader(os.Stdin)
-	client := &UCIClient{
-		search: search.New(),
-	}
+	client := newUCIClient()
 
 	for {
 		text, err := reader.ReadString('\n')
@@ -43,8 +47,73 @@ func main() {
 }
 
 type UCIClient struct {
-	position *position.Position
-	search   *search.SearchInfo
+	position     *position.Position
+	search       *search.SearchInfo
+	evaluator    *eval.PositionEvaluator // the classical scorer, kept for eval
+	network      *nnue.Network           // loaded from EvalFile, nil if there isn't one
+	useNNUE      bool
+	active       *activeSearch // the running search, nil if there isn't one
+	moveOverhead time.Duration
+}
+
+func newUCIClient() *UCIClient {
+	c := &UCIClient{
+		search:       search.New(),
+		evaluator:    eval.NewPositionEvaluator(),
+		moveOverhead: search.DefaultMoveOverhead,
+	}
+	c.search.SetScorer(c.evaluator)
+	c.search.SetListener(search.ListenerFunc(func(info search.Info) {
+		fmt.Println(formatInfo(info))
+		fmt.Println(formatOrdering(info))
+	}))
+	return c
+}
+
+// formatInfo writes search progress as an info line, i.e.
+// info depth 5 seldepth 9 score cp 30 nodes 5120 nps 40960 time 125 hashfull 2 pv e2e4 e7e5
+func formatInfo(info search.Info) string {
+	b := strings.Builder{}
+
+	b.WriteString(fmt.Sprintf("info depth %d seldepth %d", info.Depth, info.SelDepth))
+	if info.IsMate {
+		b.WriteString(fmt.Sprintf(" score mate %d", info.Mate))
+	} else {
+		b.WriteString(fmt.Sprintf(" score cp %d", info.Score))
+	}
+	b.WriteString(fmt.Sprintf(" nodes %d nps %d time %d hashfull %d", info.Nodes, info.NPS, info.Time.Milliseconds(), info.HashFull))
+
+	if len(info.PV) > 0 {
+		b.WriteString(" pv")
+		for _, mv := range info.PV {
+			b.WriteString(" " + mv.ShortString())
+		}
+	}
+
+	return b.String()
+}
+
+// formatOrdering writes how well the moves were ordered as an info string,
+// the fraction of beta cutoffs on the first move, i.e.
+// info string ordering 0.92
+func formatOrdering(info search.Info) string {
+	return fmt.Sprintf("info string ordering %.2f", info.Ordering)
+}
+
+// activeSearch tracks a search running in its own goroutine, so
+// commands like stop can still be read while it thinks
+type activeSearch struct {
+	infinite    bool
+	release     chan struct{} // closed when bestmove can be sent
+	releaseOnce sync.Once
+	done        chan struct{} // closed once bestmove was sent
+}
+
+// allowBestMove lets the search send bestmove once it finishes
+func (a *activeSearch) allowBestMove() {
+	a.releaseOnce.Do(func() {
+		close(a.release)
+	})
 }
 
 func (c *UCIClient) parseLine(line string) {
@@ -55,20 +124,39 @@ func (c *UCIClient) parseLine(line string) {
 		fmt.Println("readyok")
 	case "position":
 		fmt.Fprintln(logFile, strings.Join(segments, " "))
+		c.stopSearch()
 		c.parsePosition(segments)
 	case "ucinewgame":
+		c.stopSearch()
+		c.search.ClearHash()
 		c.parsePosition([]string{"position", "startpos"})
 	case "go":
+		c.stopSearch()
 		c.parseGo(segments)
+	case "stop":
+		c.stopSearch()
+	case "ponderhit":
+		c.ponderHit()
 	case "uci":
 		fmt.Println("id name cacti-chess")
 		fmt.Println("id author aedalus")
+		for _, o := range engineOptions {
+			fmt.Println(o)
+		}
 		fmt.Println("uciok")
+	case "setoption":
+		c.stopSearch()
+		c.parseSetOption(segments)
+	case "eval":
+		c.stopSearch()
+		c.printEval()
 	case "quit":
+		c.stopSearch()
 		os.Exit(0)
 	case "":
 	default:
-		log.Fatalf("cmd not recognized: %q", segments[0])
+		// the spec says to ignore anything unknown
+		fmt.Fprintf(logFile, "cmd not recognized: %q\n", segments[0])
 	}
 }
 
@@ -80,7 +168,7 @@ type GoCmdArgs struct {
 	Winc        time.Duration // Time increment for White
 	Binc        time.Duration // Time increment for Black
 	MovesToGo   int           // n moves til time control
-	Depth       int           // search n plys
+	Depth       int           // search n plys, 0 if not given
 	Nodes       int           // search n nodes
 	Mate        int           // search for a mate in x moves
 	MoveTime    time.Duration // search an exact duration
@@ -96,7 +184,7 @@ func parseGoCmdArgs(segments []string) GoCmdArgs {
 		Winc:        0,
 		Binc:        0,
 		MovesToGo:   0,
-		Depth:       5,
+		Depth:       0,
 		Nodes:       0,
 		Mate:        0,
 		MoveTime:    0,
@@ -145,17 +233,159 @@ func parseGoCmdArgs(segments []string) GoCmdArgs {
 	return goCmdArgs
 }
 
+// parseGo starts searching in the background. With go infinite or go
+// ponder, bestmove is held back until stop or ponderhit even if the
+// search finishes first.
 func (c *UCIClient) parseGo(segments []string) {
 	goCmdArgs := parseGoCmdArgs(segments)
 
-	c.search = search.New()
+	c.search.Clear()
 
-	_, line := c.search.SearchPosition(c.position, search.Options{
-		Depth: goCmdArgs.Depth,
-	})
+	a := &activeSearch{
+		infinite: goCmdArgs.Infinite,
+		release:  make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if !goCmdArgs.Infinite && !goCmdArgs.Ponder {
+		a.allowBestMove()
+	}
+	c.active = a
+
+	p := c.position
+	options := searchOptions(goCmdArgs, p.GetSide())
+	options.MoveOverhead = c.moveOverhead
+
+	go func() {
+		defer close(a.done)
+
+		_, line := c.search.SearchPosition(p, options)
+		<-a.release
+
+		bestMove := formatBestMove(line)
+		fmt.Fprintf(logFile, "found %s\n", bestMove)
+		fmt.Println(bestMove)
+	}()
+}
+
+// printEval writes the trace of the static evaluation of the current
+// position. It isn't part of UCI, but is handy to see why the engine
+// likes a position. GUIs won't send it, so it's only typed by hand.
+func (c *UCIClient) printEval() {
+	if c.position == nil {
+		c.parsePosition([]string{"position", "startpos"})
+	}
+	fmt.Print(c.evaluator.Trace(c.position))
+	if c.nnueActive() {
+		fmt.Printf("NNUE score: %d (white's side)\n", nnue.NewEvaluator(c.network).Evaluate(c.position))
+	}
+}
 
-	fmt.Fprintf(logFile, "found bestmove %s\n", line[0].ShortString())
-	fmt.Printf("bestmove %v\n", line[0].ShortString())
+// formatBestMove is the bestmove command for a line, with the second move to
+// ponder on. UCI uses 0000 for a null move when there's no legal move to play.
+func formatBestMove(line []position.Movekey) string {
+	switch len(line) {
+	case 0:
+		return "bestmove 0000"
+	case 1:
+		return fmt.Sprintf("bestmove %v", line[0].ShortString())
+	}
+	return fmt.Sprintf("bestmove %v ponder %v", line[0].ShortString(), line[1].ShortString())
+}
+
+// stopSearch ends any running search, waiting for it to send bestmove
+func (c *UCIClient) stopSearch() {
+	if c.active == nil {
+		return
+	}
+
+	c.search.Stop()
+	c.active.allowBestMove()
+	<-c.active.done
+	c.active = nil
+}
+
+// ponderHit means the opponent played the move we were pondering on,
+// so the search carries on as a normal search with the clock running
+func (c *UCIClient) ponderHit() {
+	if c.active == nil {
+		return
+	}
+
+	c.search.PonderHit()
+	if !c.active.infinite {
+		c.active.allowBestMove()
+	}
+}
+
+// searchOptions picks out the clock for the side to move. Without
+// a depth or any time limits the search falls back to defaultDepth.
+func searchOptions(args GoCmdArgs, side int) search.Options {
+	options := search.Options{
+		Depth:        args.Depth,
+		Infinite:     args.Infinite,
+		Ponder:       args.Ponder,
+		MovesToGo:    args.MovesToGo,
+		MoveTime:     args.MoveTime,
+		MoveOverhead: search.DefaultMoveOverhead,
+	}
+
+	if args.Nodes > 0 {
+		options.Nodes = uint64(args.Nodes)
+	}
+
+	if side == position.WHITE {
+		options.Time = args.Wtime
+		options.Increment = args.Winc
+	} else {
+		options.Time = args.Btime
+		options.Increment = args.Binc
+	}
+
+	if options.Depth == 0 && options.Nodes == 0 && options.Time == 0 && options.MoveTime == 0 && !options.Infinite && !options.Ponder {
+		options.Depth = defaultDepth
+	}
+
+	return options
+}
+
+// parseSetOptionArgs splits "setoption name <id> [value <x>]" into
+// the name + value. Both can contain spaces, i.e. "Move Overhead"
+func parseSetOptionArgs(segments []string) (name string, value string) {
+	var nameParts, valueParts []string
+	var current *[]string
+
+	for _, seg := range segments[1:] {
+		switch seg {
+		case "name":
+			current = &nameParts
+		case "value":
+			current = &valueParts
+		default:
+			if current != nil {
+				*current = append(*current, seg)
+			}
+		}
+	}
+
+	return strings.Join(nameParts, " "), strings.Join(valueParts, " ")
+}
+
+func (c *UCIClient) parseSetOption(segments []string) {
+	name, value := parseSetOptionArgs(segments)
+	fmt.Fprintf(logFile, "setoption %q = %q\n", name, value)
+
+	o, ok := findOption(name)
+	if !ok {
+		fmt.Fprintf(logFile, "option not recognized: %q\n", name)
+		return
+	}
+
+	value, err := o.parse(value)
+	if err != nil {
+		fmt.Fprintf(logFile, "error parsing option %q: %v\n", name, err)
+		return
+	}
+	o.apply(c, value)
 }
 
 func (c *UCIClient) parsePosition(segments []string) {
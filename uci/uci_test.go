@@ -1,7 +1,13 @@
 package main
 
 import (
+	"cacti-chess/engine/eval"
+	"cacti-chess/engine/position"
+	"cacti-chess/engine/search"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
 	"strings"
 	"testing"
 	"time"
@@ -17,7 +23,7 @@ func Test_parseGoLine(t *testing.T) {
 			Winc:        0,
 			Binc:        0,
 			MovesToGo:   0,
-			Depth:       3,
+			Depth:       0,
 			Nodes:       0,
 			Mate:        0,
 			MoveTime:    0,
@@ -30,6 +36,7 @@ func Test_parseGoLine(t *testing.T) {
 	t.Run("searchmoves", func(t *testing.T) {
 		want := parseGoCmdArgs([]string{})
 		want.SearchMoves = []string{"a2a4", "h7h8q"}
+		want.Infinite = true
 
 		assert.Equal(t, want, parseGoCmdArgs(strings.Split("go infinite searchmoves a2a4 h7h8q", " ")))
 	})
@@ -45,6 +52,7 @@ func Test_parseGoLine(t *testing.T) {
 		want := parseGoCmdArgs([]string{})
 		want.Wtime = time.Millisecond * 500
 		want.Btime = time.Millisecond * 1000
+		want.Infinite = true
 
 		assert.Equal(t, want, parseGoCmdArgs(strings.Split("go infinite wtime 500 btime 1000", " ")))
 	})
@@ -53,6 +61,7 @@ func Test_parseGoLine(t *testing.T) {
 		want := parseGoCmdArgs([]string{})
 		want.Winc = time.Millisecond * 500
 		want.Binc = time.Millisecond * 1000
+		want.Infinite = true
 
 		assert.Equal(t, want, parseGoCmdArgs(strings.Split("go infinite winc 500 binc 1000", " ")))
 	})
@@ -60,6 +69,7 @@ func Test_parseGoLine(t *testing.T) {
 	t.Run("movestogo", func(t *testing.T) {
 		want := parseGoCmdArgs([]string{})
 		want.MovesToGo = 23
+		want.Infinite = true
 
 		assert.Equal(t, want, parseGoCmdArgs(strings.Split("go infinite movestogo 23", " ")))
 	})
@@ -99,3 +109,201 @@ func Test_parseGoLine(t *testing.T) {
 		assert.Equal(t, want, parseGoCmdArgs(strings.Split("go infinite", " ")))
 	})
 }
+
+func Test_searchOptions(t *testing.T) {
+	t.Run("it uses the clock for the side to move", func(t *testing.T) {
+		args := parseGoCmdArgs(strings.Split("go wtime 60000 btime 30000 winc 1000 binc 500 movestogo 20", " "))
+
+		white := searchOptions(args, position.WHITE)
+		assert.Equal(t, time.Minute, white.Time)
+		assert.Equal(t, time.Second, white.Increment)
+		assert.Equal(t, 20, white.MovesToGo)
+		assert.Equal(t, 0, white.Depth)
+
+		black := searchOptions(args, position.BLACK)
+		assert.Equal(t, 30*time.Second, black.Time)
+		assert.Equal(t, 500*time.Millisecond, black.Increment)
+	})
+
+	t.Run("it falls back to the default depth without limits", func(t *testing.T) {
+		options := searchOptions(parseGoCmdArgs(strings.Split("go", " ")), position.WHITE)
+		assert.Equal(t, defaultDepth, options.Depth)
+
+		options = searchOptions(parseGoCmdArgs(strings.Split("go movetime 500", " ")), position.WHITE)
+		assert.Equal(t, 0, options.Depth)
+		assert.Equal(t, 500*time.Millisecond, options.MoveTime)
+
+		options = searchOptions(parseGoCmdArgs(strings.Split("go nodes 1000", " ")), position.WHITE)
+		assert.Equal(t, 0, options.Depth)
+		assert.Equal(t, uint64(1000), options.Nodes)
+	})
+}
+
+func Test_parseSetOptionArgs(t *testing.T) {
+	t.Run("it parses name and value", func(t *testing.T) {
+		name, value := parseSetOptionArgs(strings.Split("setoption name Hash value 64", " "))
+		assert.Equal(t, "Hash", name)
+		assert.Equal(t, "64", value)
+	})
+
+	t.Run("it parses names with spaces", func(t *testing.T) {
+		name, value := parseSetOptionArgs(strings.Split("setoption name Move Overhead value 100", " "))
+		assert.Equal(t, "Move Overhead", name)
+		assert.Equal(t, "100", value)
+	})
+
+	t.Run("it parses options without values", func(t *testing.T) {
+		name, value := parseSetOptionArgs(strings.Split("setoption name Clear Hash", " "))
+		assert.Equal(t, "Clear Hash", name)
+		assert.Equal(t, "", value)
+	})
+}
+
+// captureStdout returns everything printed while running f
+func captureStdout(t *testing.T, f func()) string {
+	r, w, err := os.Pipe()
+	require.Nil(t, err)
+
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() {
+		os.Stdout = stdout
+	}()
+
+	out := make(chan string)
+	go func() {
+		b, _ := ioutil.ReadAll(r)
+		out <- string(b)
+	}()
+
+	f()
+	w.Close()
+	return <-out
+}
+
+func TestUCIClient_search(t *testing.T) {
+	t.Run("stop ends an infinite search with a bestmove", func(t *testing.T) {
+		c := newUCIClient()
+
+		out := captureStdout(t, func() {
+			c.parseLine("position startpos moves e2e4")
+			c.parseLine("go infinite")
+			time.Sleep(100 * time.Millisecond)
+			c.parseLine("isready")
+
+			start := time.Now()
+			c.parseLine("stop")
+			assert.Less(t, time.Since(start), time.Second)
+		})
+
+		assert.Contains(t, out, "readyok")
+		assert.Contains(t, out, "info depth 1 ")
+		assert.Regexp(t, `info depth 1 .*\ninfo string ordering \d\.\d\d\n`, out)
+		assert.Contains(t, out, "bestmove ")
+		assert.Less(t, strings.Index(out, "readyok"), strings.Index(out, "bestmove "))
+		assert.Nil(t, c.active)
+	})
+
+	t.Run("ponder holds bestmove until ponderhit", func(t *testing.T) {
+		c := newUCIClient()
+
+		out := captureStdout(t, func() {
+			c.parseLine("position startpos")
+			c.parseLine("go ponder depth 1")
+			time.Sleep(100 * time.Millisecond)
+			c.parseLine("isready")
+			c.parseLine("ponderhit")
+			<-c.active.done
+		})
+
+		assert.Less(t, strings.Index(out, "readyok"), strings.Index(out, "bestmove "))
+	})
+
+	t.Run("a position without legal moves plays a null move", func(t *testing.T) {
+		c := newUCIClient()
+
+		out := captureStdout(t, func() {
+			c.parseLine("position fen R5k1/5ppp/8/8/8/8/8/6K1 b - - 0 1")
+			c.parseLine("go depth 3")
+			<-c.active.done
+		})
+
+		assert.Contains(t, out, "score mate 0 ")
+		assert.Contains(t, out, "bestmove 0000\n")
+	})
+
+	t.Run("eval traces the current position", func(t *testing.T) {
+		c := newUCIClient()
+		out := captureStdout(t, func() {
+			c.parseLine("eval")
+			c.parseLine("position fen 4k3/8/8/8/8/8/4P3/4K3 w - - 0 1")
+			c.parseLine("eval")
+		})
+
+		parts := strings.SplitN(out, "Term ", 3)
+		require.Len(t, parts, 3)
+		assert.Contains(t, parts[1], "Phase: 256/256")
+		assert.Contains(t, parts[2], "Material       |   100   100 |     0     0 |")
+		assert.Contains(t, parts[2], "Phase: 0/256")
+	})
+
+	t.Run("unknown commands are ignored", func(t *testing.T) {
+		c := newUCIClient()
+		out := captureStdout(t, func() {
+			c.parseLine("debug on")
+			c.parseLine("isready")
+		})
+		assert.Equal(t, "readyok\n", out)
+	})
+}
+
+func Test_formatInfo(t *testing.T) {
+	p, err := position.FromFen("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+	require.Nil(t, err)
+	e4, err := p.ParseMove("e2e4")
+	require.Nil(t, err)
+	p.MakeMove(e4)
+	e5, err := p.ParseMove("e7e5")
+	require.Nil(t, err)
+
+	t.Run("it formats centipawn scores", func(t *testing.T) {
+		info := search.Info{
+			Depth:    5,
+			SelDepth: 9,
+			Score:    30,
+			Nodes:    5120,
+			NPS:      40960,
+			Time:     125 * time.Millisecond,
+			HashFull: 2,
+			PV:       []position.Movekey{e4, e5},
+		}
+		assert.Equal(t, "info depth 5 seldepth 9 score cp 30 nodes 5120 nps 40960 time 125 hashfull 2 pv e2e4 e7e5", formatInfo(info))
+	})
+
+	t.Run("it formats mate scores", func(t *testing.T) {
+		info := search.Info{Depth: 4, SelDepth: 4, Score: -28998, Mate: -1, IsMate: true}
+		assert.Equal(t, "info depth 4 seldepth 4 score mate -1 nodes 0 nps 0 time 0 hashfull 0", formatInfo(info))
+
+		info = search.Info{Depth: 1, SelDepth: 1, Score: -eval.Mate, Mate: 0, IsMate: true}
+		assert.Equal(t, "info depth 1 seldepth 1 score mate 0 nodes 0 nps 0 time 0 hashfull 0", formatInfo(info))
+	})
+
+	t.Run("it formats the move ordering", func(t *testing.T) {
+		info := search.Info{Depth: 4, Ordering: 0.916}
+		assert.Equal(t, "info string ordering 0.92", formatOrdering(info))
+	})
+}
+
+func Test_formatBestMove(t *testing.T) {
+	p, err := position.FromFen("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+	require.Nil(t, err)
+	e4, err := p.ParseMove("e2e4")
+	require.Nil(t, err)
+	p.MakeMove(e4)
+	e5, err := p.ParseMove("e7e5")
+	require.Nil(t, err)
+
+	assert.Equal(t, "bestmove 0000", formatBestMove(nil))
+	assert.Equal(t, "bestmove e2e4", formatBestMove([]position.Movekey{e4}))
+	assert.Equal(t, "bestmove e2e4 ponder e7e5", formatBestMove([]position.Movekey{e4, e5}))
+}